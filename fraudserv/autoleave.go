@@ -0,0 +1,50 @@
+package fraudserv
+
+import (
+	"context"
+	"time"
+
+	"github.com/celestiaorg/go-fraud"
+)
+
+// leaveUnusedTopics periodically leaves the topics without subscriptions
+// that were not broadcast to for the idle duration set with WithAutoLeaveUnusedTopics.
+func (f *ProofService[H]) leaveUnusedTopics(ctx context.Context) {
+	ticker := time.NewTicker(f.autoLeaveIdle / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.leaveIdleTopics()
+		}
+	}
+}
+
+// leaveIdleTopics leaves the idle topics and remembers them to be re-joined on demand.
+func (f *ProofService[H]) leaveIdleTopics() {
+	now := f.clock.Now()
+	f.topicsLk.Lock()
+	defer f.topicsLk.Unlock()
+	for proofType, t := range f.topics {
+		if now.Sub(f.topicUsed[proofType]) < f.autoLeaveIdle || f.hasSubscriptions(proofType) {
+			continue
+		}
+		delete(f.topics, proofType)
+		f.idleTopics[proofType] = struct{}{}
+		if err := leave(f.pubsub, t); err != nil {
+			log.Warnw("leaving unused topic", "err", err, "proofType", proofType)
+			continue
+		}
+		log.Debugw("left unused topic", "proofType", proofType)
+	}
+}
+
+// hasSubscriptions reports whether the ProofType has any subscriptions.
+func (f *ProofService[H]) hasSubscriptions(proofType fraud.ProofType) bool {
+	f.subsLk.Lock()
+	defer f.subsLk.Unlock()
+	_, ok := f.subs[proofType]
+	return ok
+}
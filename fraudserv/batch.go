@@ -0,0 +1,88 @@
+package fraudserv
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/celestiaorg/go-header"
+
+	"github.com/celestiaorg/go-fraud"
+)
+
+// batchVerifier coalesces the proofs to verify within a window and verifies them together.
+type batchVerifier[H header.Header[H]] struct {
+	verify fraud.BatchVerifier[H]
+	window time.Duration
+
+	lk      sync.Mutex
+	pending []*batchItem[H]
+}
+
+type batchItem[H header.Header[H]] struct {
+	proof  fraud.Proof[H]
+	result chan batchResult
+}
+
+type batchResult struct {
+	ok  bool
+	err error
+}
+
+// verifier returns the Verifier adding proofs to the current batch, which is verified
+// with the given context once the window passes.
+func (b *batchVerifier[H]) verifier(ctx, batchCtx context.Context) fraud.Verifier[H] {
+	return func(proof fraud.Proof[H]) (bool, error) {
+		item := &batchItem[H]{proof: proof, result: make(chan batchResult, 1)}
+		b.lk.Lock()
+		b.pending = append(b.pending, item)
+		if len(b.pending) == 1 {
+			time.AfterFunc(b.window, func() {
+				b.flush(batchCtx)
+			})
+		}
+		b.lk.Unlock()
+
+		select {
+		case res := <-item.result:
+			return res.ok, res.err
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+// flush verifies the pending batch and hands the verdicts out.
+func (b *batchVerifier[H]) flush(ctx context.Context) {
+	b.lk.Lock()
+	items := b.pending
+	b.pending = nil
+	b.lk.Unlock()
+
+	proofs := make([]fraud.Proof[H], len(items))
+	for i, item := range items {
+		proofs[i] = item.proof
+	}
+	verdicts, err := b.run(ctx, proofs)
+	if err == nil && len(verdicts) != len(proofs) {
+		err = fmt.Errorf("batch verifier returned %d verdicts for %d proofs", len(verdicts), len(proofs))
+	}
+	for i, item := range items {
+		if err != nil {
+			item.result <- batchResult{err: err}
+			continue
+		}
+		item.result <- batchResult{ok: verdicts[i]}
+	}
+}
+
+// run runs the batch verifier, recovering its panics, as it runs outside of the processing of proofs.
+func (b *batchVerifier[H]) run(ctx context.Context, proofs []fraud.Proof[H]) (verdicts []bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("PANIC while running the batch verifier: %s", r)
+		}
+	}()
+	return b.verify(ctx, proofs)
+}
@@ -0,0 +1,141 @@
+package fraudserv
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// ErrUnblacklistUnsupported is returned by Unblacklist when the ProofService is not given
+// the Blacklist of the PubSub with WithBlacklist.
+var ErrUnblacklistUnsupported = errors.New("fraud: unblacklisting requires WithBlacklist")
+
+// Blacklist is a pubsub.Blacklist that supports removing peers from it, so that the bans of
+// the ProofService can be reversed with Unblacklist. It has to be passed to the PubSub
+// with pubsub.WithBlacklist and to the ProofService with WithBlacklist.
+type Blacklist struct {
+	lk    sync.RWMutex
+	peers map[peer.ID]struct{}
+}
+
+var _ pubsub.Blacklist = (*Blacklist)(nil)
+
+// NewBlacklist creates an empty Blacklist.
+func NewBlacklist() *Blacklist {
+	return &Blacklist{peers: make(map[peer.ID]struct{})}
+}
+
+func (b *Blacklist) Add(p peer.ID) bool {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	b.peers[p] = struct{}{}
+	return true
+}
+
+func (b *Blacklist) Contains(p peer.ID) bool {
+	b.lk.RLock()
+	defer b.lk.RUnlock()
+	_, ok := b.peers[p]
+	return ok
+}
+
+// Remove removes the peer from the Blacklist.
+func (b *Blacklist) Remove(p peer.ID) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	delete(b.peers, p)
+}
+
+// BlacklistEntry describes a peer blacklisted by the ProofService.
+type BlacklistEntry struct {
+	Peer peer.ID
+	// Reason is the reason of the decision on the proof the peer was blacklisted for.
+	Reason ProcessReason
+	// Time is when the peer was blacklisted first.
+	Time time.Time
+}
+
+// maxBlacklistedPeers bounds the amount of peers recorded for BlacklistedPeers, so that throwaway
+// peer IDs punished one after another don't grow the records without limit.
+const maxBlacklistedPeers = 1024
+
+// blacklistPeer blacklists the peer in the PubSub and records it for BlacklistedPeers,
+// dropping the record of the peer blacklisted the earliest once maxBlacklistedPeers are recorded.
+func (f *ProofService[H]) blacklistPeer(p peer.ID, reason ProcessReason) {
+	f.pubsub.BlacklistPeer(p)
+
+	f.blacklistLk.Lock()
+	defer f.blacklistLk.Unlock()
+	// the PubSub blacklist is permanent, so the first entry is kept
+	if _, ok := f.blacklisted[p]; ok {
+		return
+	}
+	if len(f.blacklisted) >= maxBlacklistedPeers {
+		var earliest *BlacklistEntry
+		for _, entry := range f.blacklisted {
+			if earliest == nil || entry.Time.Before(earliest.Time) ||
+				(entry.Time.Equal(earliest.Time) && entry.Peer < earliest.Peer) {
+				entry := entry
+				earliest = &entry
+			}
+		}
+		delete(f.blacklisted, earliest.Peer)
+	}
+	f.blacklisted[p] = BlacklistEntry{Peer: p, Reason: reason, Time: f.clock.Now()}
+}
+
+// blacklistedAmount returns the amount of peers recorded for BlacklistedPeers.
+func (f *ProofService[H]) blacklistedAmount() int {
+	f.blacklistLk.Lock()
+	defer f.blacklistLk.Unlock()
+	return len(f.blacklisted)
+}
+
+// isBlacklisted reports whether the peer is blacklisted by the ProofService.
+func (f *ProofService[H]) isBlacklisted(p peer.ID) bool {
+	f.blacklistLk.Lock()
+	defer f.blacklistLk.Unlock()
+	_, ok := f.blacklisted[p]
+	return ok
+}
+
+// BlacklistedPeers returns the peers blacklisted by the ProofService itself, in the order they
+// were blacklisted. Peers blacklisted in the PubSub by other components are not included.
+// Only the latest 1024 blacklisted peers are reported, while the earlier ones stay blacklisted
+// in the PubSub.
+func (f *ProofService[H]) BlacklistedPeers() []BlacklistEntry {
+	f.blacklistLk.Lock()
+	entries := make([]BlacklistEntry, 0, len(f.blacklisted))
+	for _, entry := range f.blacklisted {
+		entries = append(entries, entry)
+	}
+	f.blacklistLk.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].Time.Equal(entries[j].Time) {
+			return entries[i].Time.Before(entries[j].Time)
+		}
+		return entries[i].Peer < entries[j].Peer
+	})
+	return entries
+}
+
+// Unblacklist removes the peer from the Blacklist of the PubSub set with WithBlacklist
+// and from BlacklistedPeers, e.g. once the ban is confirmed to be a false positive.
+// The PubSub drops the connections of blacklisted peers, so the gossip with the peer resumes
+// once it reconnects.
+func (f *ProofService[H]) Unblacklist(p peer.ID) error {
+	if f.blacklist == nil {
+		return ErrUnblacklistUnsupported
+	}
+	f.blacklist.Remove(p)
+
+	f.blacklistLk.Lock()
+	defer f.blacklistLk.Unlock()
+	delete(f.blacklisted, p)
+	return nil
+}
@@ -0,0 +1,16 @@
+package fraudserv
+
+import "time"
+
+// Clock provides the current time to the time-dependent features of the ProofService,
+// so that tests can control the time instead of waiting for it.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the Clock of the wall time.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
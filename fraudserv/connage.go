@@ -0,0 +1,66 @@
+package fraudserv
+
+import (
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// connAges tracks since when peers are connected, as told by the Clock rather than by the connection stats,
+// so that the ages are consistent with the Clock of the ProofService.
+type connAges struct {
+	clock Clock
+
+	lk        sync.Mutex
+	connected map[peer.ID]time.Time
+}
+
+// newConnAges starts tracking the connections of the network until stop is called.
+// Peers connected already are tracked as connected since the start.
+func newConnAges(net network.Network, clock Clock) (*connAges, func()) {
+	c := &connAges{
+		clock:     clock,
+		connected: make(map[peer.ID]time.Time),
+	}
+	notifiee := &network.NotifyBundle{
+		ConnectedF:    c.onConnected,
+		DisconnectedF: c.onDisconnected,
+	}
+	net.Notify(notifiee)
+	for _, conn := range net.Conns() {
+		c.onConnected(net, conn)
+	}
+	return c, func() {
+		net.StopNotify(notifiee)
+	}
+}
+
+func (c *connAges) onConnected(_ network.Network, conn network.Conn) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	if _, ok := c.connected[conn.RemotePeer()]; !ok {
+		c.connected[conn.RemotePeer()] = c.clock.Now()
+	}
+}
+
+func (c *connAges) onDisconnected(net network.Network, conn network.Conn) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	if net.Connectedness(conn.RemotePeer()) != network.Connected {
+		delete(c.connected, conn.RemotePeer())
+	}
+}
+
+// age returns for how long the peer has been connected, or 0 if it isn't connected.
+func (c *connAges) age(pid peer.ID) time.Duration {
+	now := c.clock.Now()
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	connected, ok := c.connected[pid]
+	if !ok {
+		return 0
+	}
+	return now.Sub(connected)
+}
@@ -0,0 +1,96 @@
+package fraudserv
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// defaultSeenSetSize is the default maximum amount of digests kept by seenSet.
+const defaultSeenSetSize = 8192
+
+// seenSet is a time-bounded set of message digests used to short-circuit
+// duplicate deliveries of the same message within the window.
+// It keeps at most maxSize digests, evicting the oldest ones first,
+// so that it can't be used to exhaust memory with unique messages.
+type seenSet struct {
+	window  time.Duration
+	maxSize int
+	clock   Clock
+
+	lk   sync.Mutex
+	seen map[[sha256.Size]byte]*list.Element
+	// order keeps the seenEntries from the oldest to the newest.
+	order *list.List
+}
+
+type seenEntry struct {
+	key    [sha256.Size]byte
+	seenAt time.Time
+}
+
+func newSeenSet(window time.Duration, maxSize int, clock Clock) *seenSet {
+	if maxSize <= 0 {
+		maxSize = defaultSeenSetSize
+	}
+	return &seenSet{
+		window:  window,
+		maxSize: maxSize,
+		clock:   clock,
+		seen:    make(map[[sha256.Size]byte]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// check reports whether the data was already seen within the window
+// and marks it as seen otherwise.
+func (s *seenSet) check(data []byte) bool {
+	key := sha256.Sum256(data)
+	now := s.clock.Now()
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.prune(now)
+	if el, ok := s.seen[key]; ok {
+		if now.Sub(el.Value.(*seenEntry).seenAt) <= s.window {
+			return true
+		}
+		s.remove(el)
+	}
+	for len(s.seen) >= s.maxSize {
+		s.remove(s.order.Front())
+	}
+	s.seen[key] = s.order.PushBack(&seenEntry{key: key, seenAt: now})
+	return false
+}
+
+// size returns the amount of digests in the set.
+func (s *seenSet) size() int {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	return len(s.seen)
+}
+
+// reset removes all the entries.
+func (s *seenSet) reset() {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.seen = make(map[[sha256.Size]byte]*list.Element)
+	s.order.Init()
+}
+
+// prune removes all the entries older than the window.
+func (s *seenSet) prune(now time.Time) {
+	for el := s.order.Front(); el != nil; el = s.order.Front() {
+		if now.Sub(el.Value.(*seenEntry).seenAt) <= s.window {
+			return
+		}
+		s.remove(el)
+	}
+}
+
+func (s *seenSet) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.seen, el.Value.(*seenEntry).key)
+}
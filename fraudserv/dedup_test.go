@@ -0,0 +1,141 @@
+package fraudserv
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/go-header/headertest"
+
+	"github.com/celestiaorg/go-fraud/fraudtest"
+)
+
+func TestSeenSet(t *testing.T) {
+	clock := newFakeClock()
+	seen := newSeenSet(time.Minute, 0, clock)
+	require.False(t, seen.check([]byte("data")))
+	require.True(t, seen.check([]byte("data")))
+	require.False(t, seen.check([]byte("other")))
+
+	clock.Advance(time.Minute)
+	require.True(t, seen.check([]byte("data")))
+	clock.Advance(time.Nanosecond)
+	require.False(t, seen.check([]byte("data")))
+	// expired entries are pruned
+	require.Equal(t, 1, seen.size())
+}
+
+func TestSeenSet_Bounded(t *testing.T) {
+	seen := newSeenSet(time.Minute, 10, realClock{})
+	for i := 0; i < 1000; i++ {
+		require.False(t, seen.check([]byte(strconv.Itoa(i))))
+		require.LessOrEqual(t, seen.size(), 10)
+	}
+	// the newest are kept, while the oldest are evicted
+	require.True(t, seen.check([]byte("999")))
+	require.False(t, seen.check([]byte("0")))
+}
+
+func TestService_DedupMetrics(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+	reader := withTestMeter(t)
+
+	serv := newTestService(ctx, t, false,
+		WithDedupWindow[*headertest.DummyHeader](time.Minute),
+		WithDedupMaxSize[*headertest.DummyHeader](10))
+	require.NoError(t, serv.Start(ctx))
+
+	// flood with unique proofs
+	for height := uint64(1); height <= 100; height++ {
+		proof := fraudtest.NewValidProofAt[*headertest.DummyHeader](height)
+		msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, proof)}}
+		serv.processIncoming(ctx, fraudtest.DummyProofType, "peer", msg)
+	}
+	require.EqualValues(t, 10, sumValue(collect(ctx, t, reader), "fraud_dedup_seen"))
+}
+
+func TestService_DedupCanonical(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, WithDedupWindow[*headertest.DummyHeader](time.Minute))
+	require.NoError(t, serv.Start(ctx))
+
+	// the same invalid proof, encoded differently
+	msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: []byte(`{"Valid":false}`)}}
+	res := serv.processIncoming(ctx, fraudtest.DummyProofType, "peer", msg)
+	require.Equal(t, pubsub.ValidationReject, res)
+	msg = &pubsub.Message{Message: &pubsub_pb.Message{Data: []byte(`{"Panics":false,"Valid":false}`)}}
+	res = serv.processIncoming(ctx, fraudtest.DummyProofType, "peer", msg)
+	require.Equal(t, pubsub.ValidationIgnore, res)
+}
+
+func TestService_DedupWindowExpiry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	clock := newFakeClock()
+	serv := newTestService(ctx, t, false,
+		WithDedupWindow[*headertest.DummyHeader](time.Hour),
+		WithClock[*headertest.DummyHeader](clock))
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewInvalidProof[*headertest.DummyHeader]()
+	msg := func() *pubsub.Message {
+		return &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, proof)}}
+	}
+	require.Equal(t, pubsub.ValidationReject, serv.processIncoming(ctx, proof.Type(), "peer", msg()))
+	require.Equal(t, pubsub.ValidationIgnore, serv.processIncoming(ctx, proof.Type(), "peer", msg()))
+
+	// once the window passes, the message is validated again
+	clock.Advance(time.Hour + time.Second)
+	require.Equal(t, pubsub.ValidationReject, serv.processIncoming(ctx, proof.Type(), "peer", msg()))
+}
+
+// fakeClock is a Clock that only moves when advanced.
+type fakeClock struct {
+	lk  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestService_DedupWindow(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, WithDedupWindow[*headertest.DummyHeader](time.Minute))
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewInvalidProof[*headertest.DummyHeader]()
+	msg := func() *pubsub.Message {
+		return &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, proof)}}
+	}
+
+	res := serv.processIncoming(ctx, proof.Type(), "peer", msg())
+	require.Equal(t, pubsub.ValidationReject, res)
+	// the duplicate is ignored without being validated again
+	res = serv.processIncoming(ctx, proof.Type(), "peer", msg())
+	require.Equal(t, pubsub.ValidationIgnore, res)
+}
@@ -0,0 +1,80 @@
+package fraudserv
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-datastore"
+	q "github.com/ipfs/go-datastore/query"
+)
+
+var errCiphertextTooShort = errors.New("fraud: encrypted proof is too short")
+
+// encryptedDatastore wraps a datastore to encrypt values at rest using AES-GCM.
+// Every value is stored in an envelope of a random nonce followed by the ciphertext,
+// which is authenticated along with its key, so that it can't be moved under another key.
+type encryptedDatastore struct {
+	datastore.Datastore
+	aead cipher.AEAD
+}
+
+func newEncryptedDatastore(ds datastore.Datastore, key []byte) (*encryptedDatastore, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedDatastore{Datastore: ds, aead: aead}, nil
+}
+
+func (e *encryptedDatastore) Put(ctx context.Context, key datastore.Key, value []byte) error {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	return e.Datastore.Put(ctx, key, e.aead.Seal(nonce, nonce, value, key.Bytes()))
+}
+
+func (e *encryptedDatastore) Get(ctx context.Context, key datastore.Key) ([]byte, error) {
+	value, err := e.Datastore.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return e.decrypt(key, value)
+}
+
+func (e *encryptedDatastore) Query(ctx context.Context, query q.Query) (q.Results, error) {
+	results, err := e.Datastore.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, err
+	}
+	if !query.KeysOnly {
+		for i := range entries {
+			entries[i].Value, err = e.decrypt(datastore.NewKey(entries[i].Key), entries[i].Value)
+			if err != nil {
+				return nil, fmt.Errorf("decrypting %s: %w", entries[i].Key, err)
+			}
+		}
+	}
+	return q.ResultsWithEntries(query, entries), nil
+}
+
+func (e *encryptedDatastore) decrypt(key datastore.Key, envelope []byte) ([]byte, error) {
+	size := e.aead.NonceSize()
+	if len(envelope) < size {
+		return nil, errCiphertextTooShort
+	}
+	return e.aead.Open(nil, envelope[:size], envelope[size:], key.Bytes())
+}
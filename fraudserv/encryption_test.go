@@ -0,0 +1,104 @@
+package fraudserv
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	ds_sync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/go-header/headertest"
+
+	"github.com/celestiaorg/go-fraud/fraudtest"
+)
+
+func TestEncryptedDatastore_RoundTrip(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	bin := mustMarshal(t, proof)
+
+	raw := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	encrypted, err := newEncryptedDatastore(raw, bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+	store := namespace.Wrap(encrypted, makeKey(storePrefix, proof.Type()))
+
+	require.NoError(t, put(ctx, store, string(proof.HeaderHash()), bin))
+
+	data, err := getByHash(ctx, store, string(proof.HeaderHash()))
+	require.NoError(t, err)
+	require.Equal(t, bin, data)
+
+	proofs, err := getAll[*headertest.DummyHeader](ctx, store, proof.Type(), unmarshaler, 0)
+	require.NoError(t, err)
+	require.Len(t, proofs, 1)
+
+	// the proof is not stored in plain
+	data, err = getByHash(ctx, namespace.Wrap(raw, makeKey(storePrefix, proof.Type())), string(proof.HeaderHash()))
+	require.NoError(t, err)
+	require.NotEqual(t, bin, data)
+}
+
+func TestEncryptedDatastore_WrongKey(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	raw := ds_sync.MutexWrap(datastore.NewMapDatastore())
+
+	encrypted, err := newEncryptedDatastore(raw, bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+	store := namespace.Wrap(encrypted, makeKey(storePrefix, proof.Type()))
+	require.NoError(t, put(ctx, store, string(proof.HeaderHash()), mustMarshal(t, proof)))
+
+	encrypted, err = newEncryptedDatastore(raw, bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+	store = namespace.Wrap(encrypted, makeKey(storePrefix, proof.Type()))
+
+	_, err = getByHash(ctx, store, string(proof.HeaderHash()))
+	require.Error(t, err)
+	_, err = getAll[*headertest.DummyHeader](ctx, store, proof.Type(), unmarshaler, 0)
+	require.Error(t, err)
+}
+
+func TestService_StoreEncryption(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, WithStoreEncryption[*headertest.DummyHeader](bytes.Repeat([]byte{1}, 16)))
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, serv.Broadcast(ctx, proof))
+
+	proofs, err := serv.Get(ctx, proof.Type())
+	require.NoError(t, err)
+	require.Len(t, proofs, 1)
+}
+
+func TestEncryptedDatastore_MovedValue(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	raw := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	encrypted, err := newEncryptedDatastore(raw, bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+	store := namespace.Wrap(encrypted, makeKey(storePrefix, proof.Type()))
+	require.NoError(t, put(ctx, store, string(proof.HeaderHash()), mustMarshal(t, proof)))
+
+	// the encrypted proof moved under another key doesn't decrypt
+	rawStore := namespace.Wrap(raw, makeKey(storePrefix, proof.Type()))
+	data, err := getByHash(ctx, rawStore, string(proof.HeaderHash()))
+	require.NoError(t, err)
+	require.NoError(t, put(ctx, rawStore, "moved", data))
+	_, err = getByHash(ctx, store, "moved")
+	require.Error(t, err)
+	_, err = getAll[*headertest.DummyHeader](ctx, store, proof.Type(), unmarshaler, 0)
+	require.Error(t, err)
+}
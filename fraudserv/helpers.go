@@ -1,11 +1,14 @@
 package fraudserv
 
 import (
-	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"strings"
+	"unicode"
 
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
-	"github.com/libp2p/go-libp2p/core/peer"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
 	"github.com/libp2p/go-libp2p/core/protocol"
 
 	"github.com/celestiaorg/go-fraud"
@@ -19,23 +22,42 @@ func protocolID(networkID string) protocol.ID {
 	return protocol.ID(fmt.Sprintf("/%s/fraud/v0.0.1", networkID))
 }
 
+// ContentMessageID derives the pubsub message ID from the hash of the message content,
+// so that identical proofs published by different peers get the same ID.
+func ContentMessageID(msg *pubsub_pb.Message) string {
+	hash := sha256.Sum256(msg.GetData())
+	return string(hash[:])
+}
+
+// validateName checks whether the name can be safely used as a part of libp2p protocol IDs,
+// pubsub topics and datastore keys.
+func validateName(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("fraud: empty %s", kind)
+	}
+	if strings.Contains(name, "/") {
+		return fmt.Errorf("fraud: %s %q must not contain slashes", kind, name)
+	}
+	if strings.IndexFunc(name, func(r rune) bool { return !unicode.IsPrint(r) || unicode.IsSpace(r) }) != -1 {
+		return fmt.Errorf("fraud: %s %q must not contain whitespace or non-printable characters", kind, name)
+	}
+	return nil
+}
+
+// join joins the topic of the ProofType, leaving the registration of its validator to the caller.
 func join(
 	p *pubsub.PubSub,
 	proofType fraud.ProofType,
 	networkID string,
-	validate func(context.Context, fraud.ProofType, peer.ID, *pubsub.Message) pubsub.ValidationResult,
+	opts ...pubsub.TopicOpt,
 ) (*pubsub.Topic, error) {
 	topic := PubsubTopicID(proofType.String(), networkID)
 	log.Infow("joining topic", "id", topic)
-	t, err := p.Join(topic)
-	if err != nil {
-		return nil, err
-	}
-	err = p.RegisterTopicValidator(
-		topic,
-		func(ctx context.Context, from peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
-			return validate(ctx, proofType, from, msg)
-		},
-	)
-	return t, err
+	return p.Join(topic, opts...)
+}
+
+// leave closes the topic and unregisters its validator.
+func leave(p *pubsub.PubSub, t *pubsub.Topic) error {
+	err := p.UnregisterTopicValidator(t.String())
+	return errors.Join(err, t.Close())
 }
@@ -0,0 +1,290 @@
+package fraudserv
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/celestiaorg/go-fraud"
+)
+
+var meter = otel.Meter("fraudserv")
+
+// subscriptionMetrics reports buffered subscriptions falling behind.
+type subscriptionMetrics struct {
+	dropped metric.Int64Counter
+	reg     metric.Registration
+}
+
+// newSubscriptionMetrics registers subscription metrics.
+// The occupancy func reports the amount of buffered messages per ProofType.
+func newSubscriptionMetrics(occupancy func() map[fraud.ProofType]int) (*subscriptionMetrics, error) {
+	dropped, err := meter.Int64Counter("fraud_subscription_dropped",
+		metric.WithDescription("Messages dropped due to full subscription buffers"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	buffered, err := meter.Int64ObservableGauge("fraud_subscription_buffered",
+		metric.WithDescription("Messages buffered in subscriptions"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	callback := func(_ context.Context, observer metric.Observer) error {
+		for proofType, amount := range occupancy() {
+			observer.ObserveInt64(buffered, int64(amount),
+				metric.WithAttributes(attribute.String("proof_type", string(proofType))))
+		}
+		return nil
+	}
+	reg, err := meter.RegisterCallback(callback, buffered)
+	if err != nil {
+		return nil, err
+	}
+	return &subscriptionMetrics{dropped: dropped, reg: reg}, nil
+}
+
+func (m *subscriptionMetrics) observeDrop(ctx context.Context, proofType fraud.ProofType) {
+	if m == nil {
+		return
+	}
+	m.dropped.Add(ctx, 1,
+		metric.WithAttributes(attribute.String("proof_type", string(proofType))))
+}
+
+func (m *subscriptionMetrics) close() error {
+	if m == nil {
+		return nil
+	}
+	return m.reg.Unregister()
+}
+
+// newSeenSetMetrics registers the metric of the amount of messages remembered for deduplication.
+func newSeenSetMetrics(size func() int) (metric.Registration, error) {
+	seen, err := meter.Int64ObservableGauge("fraud_dedup_seen",
+		metric.WithDescription("Messages remembered for deduplication"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	callback := func(_ context.Context, observer metric.Observer) error {
+		observer.ObserveInt64(seen, int64(size()))
+		return nil
+	}
+	return meter.RegisterCallback(callback, seen)
+}
+
+// newBlacklistMetrics registers the metric of the amount of peers recorded for BlacklistedPeers.
+func newBlacklistMetrics(size func() int) (metric.Registration, error) {
+	blacklisted, err := meter.Int64ObservableGauge("fraud_blacklisted_peers",
+		metric.WithDescription("Peers blacklisted by the service"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	callback := func(_ context.Context, observer metric.Observer) error {
+		observer.ObserveInt64(blacklisted, int64(size()))
+		return nil
+	}
+	return meter.RegisterCallback(callback, blacklisted)
+}
+
+// newProofAgeMetrics registers the metric of the age of the oldest stored proof per ProofType.
+// The oldest func reports when the oldest stored proof of every ProofType was first seen.
+func newProofAgeMetrics(
+	clock Clock,
+	oldest func(context.Context) map[fraud.ProofType]time.Time,
+) (metric.Registration, error) {
+	age, err := meter.Int64ObservableGauge("fraud_oldest_proof_age",
+		metric.WithDescription("Age of the oldest stored proof since it was first seen"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	callback := func(ctx context.Context, observer metric.Observer) error {
+		now := clock.Now()
+		for proofType, firstSeen := range oldest(ctx) {
+			observer.ObserveInt64(age, int64(now.Sub(firstSeen)/time.Second),
+				metric.WithAttributes(attribute.String("proof_type", string(proofType))))
+		}
+		return nil
+	}
+	return meter.RegisterCallback(callback, age)
+}
+
+// storeFullMetrics counts proofs refused as the store holds the amount of proofs set with WithMaxTotalProofs.
+type storeFullMetrics struct {
+	refused metric.Int64Counter
+}
+
+func newStoreFullMetrics() (*storeFullMetrics, error) {
+	refused, err := meter.Int64Counter("fraud_store_full_refused",
+		metric.WithDescription("Proofs refused as the store is full"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &storeFullMetrics{refused: refused}, nil
+}
+
+func (m *storeFullMetrics) observeRefused(ctx context.Context, proofType fraud.ProofType) {
+	if m == nil {
+		return
+	}
+	m.refused.Add(ctx, 1,
+		metric.WithAttributes(attribute.String("proof_type", string(proofType))))
+}
+
+// unknownTypeWarnInterval is the minimal interval between warnings about proofs of the same type
+// without an unmarshaler.
+const unknownTypeWarnInterval = time.Minute
+
+// unknownTypes counts proofs of types without an unmarshaler, which is a sign of peers running
+// a newer version or of a misconfiguration, and warns about them without flooding the logs.
+type unknownTypes struct {
+	received metric.Int64Counter
+	clock    Clock
+
+	lk     sync.Mutex
+	warned map[fraud.ProofType]time.Time
+}
+
+func newUnknownTypes(clock Clock) (*unknownTypes, error) {
+	received, err := meter.Int64Counter("fraud_unknown_proof_type",
+		metric.WithDescription("Proofs received of types without an unmarshaler"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &unknownTypes{
+		received: received,
+		clock:    clock,
+		warned:   make(map[fraud.ProofType]time.Time),
+	}, nil
+}
+
+// observe counts the proof of the unknown ProofType and warns about it, unless it was recently done
+// for the ProofType.
+func (u *unknownTypes) observe(ctx context.Context, proofType fraud.ProofType, from peer.ID) {
+	if u == nil {
+		return
+	}
+	u.received.Add(ctx, 1,
+		metric.WithAttributes(attribute.String("proof_type", string(proofType))))
+
+	now := u.clock.Now()
+	u.lk.Lock()
+	last, ok := u.warned[proofType]
+	warn := !ok || now.Sub(last) >= unknownTypeWarnInterval
+	if warn {
+		u.warned[proofType] = now
+	}
+	u.lk.Unlock()
+	if warn {
+		log.Warnw("received proof of a type without an unmarshaler, peers may run a newer version",
+			"proofType", proofType, "peer", from)
+	}
+}
+
+// syncMetrics reports the activity of the syncer, so that operators can alert on stalled syncs.
+type syncMetrics struct {
+	attempts  metric.Int64Counter
+	successes metric.Int64Counter
+	failures  metric.Int64Counter
+	fetched   metric.Int64Counter
+	clock     Clock
+
+	// lastSuccess is the time of the last successful sync in Unix seconds, 0 if none yet.
+	lastSuccess atomic.Int64
+	reg         metric.Registration
+}
+
+func newSyncMetrics(clock Clock) (*syncMetrics, error) {
+	attempts, err := meter.Int64Counter("fraud_sync_attempts",
+		metric.WithDescription("Proof requests sent to peers by the syncer"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	successes, err := meter.Int64Counter("fraud_sync_successes",
+		metric.WithDescription("Proof requests to peers that succeeded"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	failures, err := meter.Int64Counter("fraud_sync_failures",
+		metric.WithDescription("Proof requests to peers that failed"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	fetched, err := meter.Int64Counter("fraud_sync_fetched",
+		metric.WithDescription("Proofs fetched from peers by the syncer"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	lastSuccess, err := meter.Int64ObservableGauge("fraud_sync_last_success",
+		metric.WithDescription("Time of the last successful sync in Unix seconds"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	m := &syncMetrics{
+		attempts:  attempts,
+		successes: successes,
+		failures:  failures,
+		fetched:   fetched,
+		clock:     clock,
+	}
+	callback := func(_ context.Context, observer metric.Observer) error {
+		if last := m.lastSuccess.Load(); last != 0 {
+			observer.ObserveInt64(lastSuccess, last)
+		}
+		return nil
+	}
+	m.reg, err = meter.RegisterCallback(callback, lastSuccess)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *syncMetrics) observeAttempt(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	m.attempts.Add(ctx, 1)
+}
+
+// observeResult counts the outcome of the proof request and the proofs fetched per ProofType.
+func (m *syncMetrics) observeResult(ctx context.Context, fetched map[fraud.ProofType]int, err error) {
+	if m == nil {
+		return
+	}
+	if err != nil {
+		m.failures.Add(ctx, 1)
+		return
+	}
+	m.successes.Add(ctx, 1)
+	m.lastSuccess.Store(m.clock.Now().Unix())
+	for proofType, amount := range fetched {
+		m.fetched.Add(ctx, int64(amount),
+			metric.WithAttributes(attribute.String("proof_type", string(proofType))))
+	}
+}
+
+func (m *syncMetrics) close() error {
+	if m == nil {
+		return nil
+	}
+	return m.reg.Unregister()
+}
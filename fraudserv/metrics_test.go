@@ -0,0 +1,166 @@
+package fraudserv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/celestiaorg/go-header/headertest"
+
+	"github.com/celestiaorg/go-fraud/fraudtest"
+)
+
+func TestService_SubscriptionDropMetrics(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+	reader := withTestMeter(t)
+
+	serv := newTestService(ctx, t, false,
+		WithBufferedSubscriptions[*headertest.DummyHeader](1),
+		withDistinctMessages())
+	require.NoError(t, serv.Start(ctx))
+
+	sub, err := serv.Subscribe(fraudtest.DummyProofType)
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	// nobody reads the subscription, so all proofs after the first one overflow the buffer
+	for height := uint64(1); height <= 3; height++ {
+		proof := fraudtest.NewValidProofAt[*headertest.DummyHeader](height)
+		require.NoError(t, serv.Broadcast(ctx, proof))
+	}
+
+	require.Eventually(t, func() bool {
+		return sumValue(collect(ctx, t, reader), "fraud_subscription_dropped") == 2
+	}, time.Second, time.Millisecond*10)
+	require.EqualValues(t, 1, sumValue(collect(ctx, t, reader), "fraud_subscription_buffered"))
+
+	proof, err := sub.Proof(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, proof.Height())
+	require.EqualValues(t, 0, sumValue(collect(ctx, t, reader), "fraud_subscription_buffered"))
+}
+
+func TestService_UnknownProofTypeMetrics(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+	reader := withTestMeter(t)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	proof := &unknownProof{fraudtest.NewValidProof[*headertest.DummyHeader]()}
+	for i := 0; i < 2; i++ {
+		msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, proof)}}
+		res := serv.processIncoming(ctx, proof.Type(), "peer", msg)
+		require.Equal(t, pubsub.ValidationReject, res)
+	}
+	require.EqualValues(t, 2, sumValue(collect(ctx, t, reader), "fraud_unknown_proof_type"))
+}
+
+func TestService_SyncMetrics(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+	reader := withTestMeter(t)
+
+	net, err := mocknet.FullMeshLinked(2)
+	require.NoError(t, err)
+
+	servA := newTestServiceWithHost(ctx, t, net.Hosts()[0], false)
+	require.NoError(t, servA.Start(ctx))
+	require.NoError(t, servA.Broadcast(ctx, fraudtest.NewValidProof[*headertest.DummyHeader]()))
+
+	servB := newTestServiceWithHost(ctx, t, net.Hosts()[1], true)
+	require.NoError(t, servB.Start(ctx))
+	require.Zero(t, sumValue(collect(ctx, t, reader), "fraud_sync_last_success"))
+
+	_, err = net.ConnectPeers(net.Hosts()[0].ID(), net.Hosts()[1].ID())
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return sumValue(collect(ctx, t, reader), "fraud_sync_fetched") == 1
+	}, time.Second*2, time.Millisecond*10)
+
+	rm := collect(ctx, t, reader)
+	require.EqualValues(t, 1, sumValue(rm, "fraud_sync_attempts"))
+	require.EqualValues(t, 1, sumValue(rm, "fraud_sync_successes"))
+	require.Zero(t, sumValue(rm, "fraud_sync_failures"))
+	require.Positive(t, sumValue(rm, "fraud_sync_last_success"))
+}
+
+func TestService_OldestProofAgeMetrics(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+	reader := withTestMeter(t)
+
+	clock := newFakeClock()
+	serv := newTestService(ctx, t, false,
+		WithClock[*headertest.DummyHeader](clock),
+		WithTypeQuota[*headertest.DummyHeader](fraudtest.DummyProofType, 10))
+	require.NoError(t, serv.Start(ctx))
+	require.Zero(t, sumValue(collect(ctx, t, reader), "fraud_oldest_proof_age"))
+
+	for height := uint64(1); height <= 3; height++ {
+		_, err := serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](height))
+		require.NoError(t, err)
+		clock.Advance(time.Minute)
+	}
+	// the oldest proof was stored 3 minutes ago
+	require.EqualValues(t, 180, sumValue(collect(ctx, t, reader), "fraud_oldest_proof_age"))
+
+	clock.Advance(time.Minute)
+	require.EqualValues(t, 240, sumValue(collect(ctx, t, reader), "fraud_oldest_proof_age"))
+
+	// corrupted timestamps are skipped
+	corrupted := datastore.NewKey("corrupted")
+	require.NoError(t, serv.firstSeenStore(fraudtest.DummyProofType).Put(ctx, corrupted, []byte{1, 2}))
+	require.EqualValues(t, 240, sumValue(collect(ctx, t, reader), "fraud_oldest_proof_age"))
+}
+
+// withTestMeter replaces the package meter with one backed by a manual reader.
+func withTestMeter(t *testing.T) sdkmetric.Reader {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	prev := meter
+	meter = provider.Meter("fraudserv")
+	t.Cleanup(func() {
+		meter = prev
+	})
+	return reader
+}
+
+func collect(ctx context.Context, t *testing.T, reader sdkmetric.Reader) metricdata.ResourceMetrics {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	return rm
+}
+
+// sumValue sums the data points of a counter or a gauge, or returns 0 if there are none.
+func sumValue(rm metricdata.ResourceMetrics, name string) int64 {
+	var total int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					total += dp.Value
+				}
+			case metricdata.Gauge[int64]:
+				for _, dp := range data.DataPoints {
+					total += dp.Value
+				}
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,430 @@
+package fraudserv
+
+import (
+	"context"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/sync"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/celestiaorg/go-header"
+
+	"github.com/celestiaorg/go-fraud"
+)
+
+// Option is a functional option that configures the ProofService.
+type Option[H header.Header[H]] func(*ProofService[H])
+
+// WithRemoteKnownCheck enables Known to additionally ask connected peers
+// whether they have the proof when it is not found locally.
+func WithRemoteKnownCheck[H header.Header[H]](enabled bool) Option[H] {
+	return func(f *ProofService[H]) {
+		f.remoteKnownCheck = enabled
+	}
+}
+
+// WithPeriodicRebroadcast enables periodic re-publishing of locally stored proofs
+// on their topics, so that peers joining after a proof was gossiped eventually receive it.
+// It is useful for networks without the syncer enabled.
+func WithPeriodicRebroadcast[H header.Header[H]](interval time.Duration) Option[H] {
+	return func(f *ProofService[H]) {
+		f.rebroadcastInterval = interval
+	}
+}
+
+// WithInMemoryStore makes the ProofService keep proofs in memory only,
+// overriding any provided datastore, so that nil may be passed instead.
+// NOTE: Proofs are not persisted and are lost on restart.
+func WithInMemoryStore[H header.Header[H]]() Option[H] {
+	return func(f *ProofService[H]) {
+		f.ds = sync.MutexWrap(datastore.NewMapDatastore())
+	}
+}
+
+// WithStoreEncryption encrypts stored proofs at rest with AES-GCM using the given key.
+// The key must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+// NewProofService panics otherwise. Proofs stored with the key can't be read without it.
+func WithStoreEncryption[H header.Header[H]](key []byte) Option[H] {
+	return func(f *ProofService[H]) {
+		f.encryptionKey = key
+	}
+}
+
+// WithKeyHasher sets the function deriving datastore keys from proof header hashes.
+// By default, keys are hex encoded header hashes. See SHA256KeyHasher for a fixed-length scheme.
+// NOTE: Changing the hasher for an existing store requires ProofService.MigrateKeys.
+func WithKeyHasher[H header.Header[H]](hasher func([]byte) string) Option[H] {
+	return func(f *ProofService[H]) {
+		f.keyHasher = hasher
+	}
+}
+
+// WithDedupWindow makes the ProofService ignore duplicate deliveries of the same proof
+// within the given window before validating them. Proofs are compared by their fraud.CanonicalBytes.
+func WithDedupWindow[H header.Header[H]](window time.Duration) Option[H] {
+	return func(f *ProofService[H]) {
+		f.dedupWindow = window
+	}
+}
+
+// WithDedupMaxSize limits the amount of messages remembered for WithDedupWindow.
+// Once the limit is reached, the oldest messages are forgotten first. It defaults to 8192.
+func WithDedupMaxSize[H header.Header[H]](size int) Option[H] {
+	return func(f *ProofService[H]) {
+		f.dedupMaxSize = size
+	}
+}
+
+// WithMessageIdFn sets the function deriving pubsub message IDs for the proof topics.
+// Use ContentMessageID to make pubsub deduplicate identical proofs published by different peers.
+func WithMessageIdFn[H header.Header[H]](fn pubsub.MsgIdFunction) Option[H] { //nolint:revive,stylecheck
+	return func(f *ProofService[H]) {
+		f.msgIDFn = fn
+	}
+}
+
+// WithBufferedSubscriptions makes subscriptions read proofs ahead into a buffer of the given size.
+// Once the buffer of a slow subscriber is full, new proofs are dropped for it and counted
+// in metrics, instead of stalling delivery.
+func WithBufferedSubscriptions[H header.Header[H]](size int) Option[H] {
+	return func(f *ProofService[H]) {
+		f.subBufferSize = size
+	}
+}
+
+// WithoutHeadThreshold disables the check of proof heights against the network head,
+// so that the ProofService works without tracking the head and nil HeadGetter may be passed.
+// NOTE: Proofs are then checked solely by verifiers and Validate. Proofs for heights far
+// in the future are not rejected early and cost a header fetch each, so this should only be
+// used by nodes receiving proofs from trusted peers.
+func WithoutHeadThreshold[H header.Header[H]]() Option[H] {
+	return func(f *ProofService[H]) {
+		f.skipHeadThreshold = true
+	}
+}
+
+// WithHeadThresholdFor sets the maximum height of Proofs of the given ProofType
+// relative to the network head, overriding the default of 20 for the ProofType.
+func WithHeadThresholdFor[H header.Header[H]](proofType fraud.ProofType, threshold uint64) Option[H] {
+	return func(f *ProofService[H]) {
+		if f.headThresholds == nil {
+			f.headThresholds = make(map[fraud.ProofType]uint64)
+		}
+		f.headThresholds[proofType] = threshold
+	}
+}
+
+// WithHeaderFetchers replaces the HeaderFetcher passed to NewProofService with the given ones,
+// tried in order until one succeeds, e.g. a hot store followed by a cold archival store.
+// The error of the last HeaderFetcher is returned if none succeeds.
+func WithHeaderFetchers[H header.Header[H]](fetchers ...fraud.HeaderFetcher[H]) Option[H] {
+	return func(f *ProofService[H]) {
+		f.headerGetter = func(ctx context.Context, height uint64) (h H, err error) {
+			for _, fetch := range fetchers {
+				h, err = fetch(ctx, height)
+				if err == nil {
+					return h, nil
+				}
+			}
+			return h, err
+		}
+	}
+}
+
+// WithSyncProgress sets the callback reporting the total amount of proofs of a ProofType
+// fetched from peers so far, invoked as proofs are fetched during sync.
+// The callback may be invoked concurrently.
+func WithSyncProgress[H header.Header[H]](progress func(proofType fraud.ProofType, fetched int)) Option[H] {
+	return func(f *ProofService[H]) {
+		f.syncProgress = progress
+	}
+}
+
+// WithSubscriptionReplay makes new subscriptions receive the already stored proofs of their
+// ProofType before the live ones, so that late subscribers catch up. Each proof is delivered
+// to a subscription once, even if it is received live while being replayed.
+func WithSubscriptionReplay[H header.Header[H]]() Option[H] {
+	return func(f *ProofService[H]) {
+		f.subReplay = true
+	}
+}
+
+// WithStoreNamespace sets the root datastore key under which proofs are stored,
+// e.g. "/myapp/fraud", so that the datastore can be shared with other subsystems.
+// It defaults to "/fraud".
+// NOTE: Proofs stored under the previous root are not visible under the new one.
+// To keep them, copy the entries from "/<old root>/<proof type>" to "/<new root>/<proof type>"
+// before starting the ProofService.
+func WithStoreNamespace[H header.Header[H]](root string) Option[H] {
+	return func(f *ProofService[H]) {
+		f.storeNamespace = root
+	}
+}
+
+// WithReadOnlyStore makes the ProofService validate and re-gossip proofs without persisting them,
+// e.g. for replicas with the store mounted read-only, instead of failing every write.
+func WithReadOnlyStore[H header.Header[H]](readOnly bool) Option[H] {
+	return func(f *ProofService[H]) {
+		f.readOnlyStore = readOnly
+	}
+}
+
+// WithClock sets the Clock used by the time-dependent features, e.g. WithDedupWindow.
+// It defaults to the wall time and is meant for tests.
+func WithClock[H header.Header[H]](clock Clock) Option[H] {
+	return func(f *ProofService[H]) {
+		f.clock = clock
+	}
+}
+
+// WithTypeQuota limits the amount of stored proofs of the given ProofType.
+// Once the quota would be exceeded, the proofs seen first are evicted.
+// The age of the oldest stored proof of the ProofType is reported as the fraud_oldest_proof_age metric.
+func WithTypeQuota[H header.Header[H]](proofType fraud.ProofType, maxProofs int) Option[H] {
+	return func(f *ProofService[H]) {
+		if f.typeQuotas == nil {
+			f.typeQuotas = make(map[fraud.ProofType]int)
+		}
+		f.typeQuotas[proofType] = maxProofs
+	}
+}
+
+// UnmarshalFailurePolicy defines how the ProofService treats peers
+// sending proofs that fail to unmarshal.
+type UnmarshalFailurePolicy int
+
+const (
+	// UnmarshalFailureBlacklist rejects the proof and blacklists the peer. It is the default.
+	UnmarshalFailureBlacklist UnmarshalFailurePolicy = iota
+	// UnmarshalFailureIgnore ignores the proof without penalizing the peer,
+	// e.g. during rolling upgrades changing the proof format.
+	UnmarshalFailureIgnore
+	// UnmarshalFailureGraylist rejects the proof without blacklisting the peer,
+	// leaving the penalty to the peer scoring of the router, if any.
+	UnmarshalFailureGraylist
+)
+
+// WithUnmarshalFailurePolicy sets how peers sending proofs that fail to unmarshal are treated.
+// Proofs of types without an unmarshaler are always rejected without blacklisting.
+func WithUnmarshalFailurePolicy[H header.Header[H]](policy UnmarshalFailurePolicy) Option[H] {
+	return func(f *ProofService[H]) {
+		f.unmarshalFailurePolicy = policy
+	}
+}
+
+// WithSyncPeerSelector sets the selector of peers to request proofs from during sync, e.g. to prefer
+// peers with the best reputation. It is invoked on every newly identified peer and the returned
+// peers are requested in order, skipping already requested ones. By default, every newly
+// identified peer is requested.
+func WithSyncPeerSelector[H header.Header[H]](selector func(context.Context) []peer.ID) Option[H] {
+	return func(f *ProofService[H]) {
+		f.syncPeerSelector = selector
+	}
+}
+
+// WithSyncWrites makes the ProofService Flush the datastore after every stored proof,
+// so that accepted proofs are not lost on a crash with datastores buffering writes.
+func WithSyncWrites[H header.Header[H]](syncWrites bool) Option[H] {
+	return func(f *ProofService[H]) {
+		f.syncWrites = syncWrites
+	}
+}
+
+// WithAutoLeaveUnusedTopics makes the ProofService leave the topics without subscriptions
+// that were not broadcast to for the given idle duration, e.g. on resource-constrained nodes.
+// Proofs of the left topics are neither received nor validated until the topics are re-joined
+// on the next Subscribe or Broadcast.
+func WithAutoLeaveUnusedTopics[H header.Header[H]](idle time.Duration) Option[H] {
+	return func(f *ProofService[H]) {
+		f.autoLeaveIdle = idle
+	}
+}
+
+// WithConnectReconciliation makes the ProofService exchange compact inventories of stored proofs
+// with every newly connected peer and pull the proofs missing locally, instead of relying
+// on gossip and the one-shot sync only. Both peers must enable it to serve their inventories.
+func WithConnectReconciliation[H header.Header[H]](reconcile bool) Option[H] {
+	return func(f *ProofService[H]) {
+		f.connectReconciliation = reconcile
+	}
+}
+
+// WithResultPolicy sets the policy mapping the reasons of not accepting incoming proofs to
+// the pubsub ValidationResults, e.g. to ignore proofs failing in verifiers instead of rejecting
+// them. Peers are blacklisted for invalid proofs only if the policy rejects them. The policy
+// can't accept proofs, acceptance is treated as ValidationIgnore. It defaults to DefaultResultPolicy.
+func WithResultPolicy[H header.Header[H]](policy func(reason ProcessReason) pubsub.ValidationResult) Option[H] {
+	return func(f *ProofService[H]) {
+		f.resultPolicy = policy
+	}
+}
+
+// WithSubscriptionBufferSize sets the size of the buffer of the underlying pubsub subscriptions,
+// which defaults to 32 messages. Messages are dropped by pubsub once the buffer is full, so
+// high-throughput topics may need a bigger one. Every buffered message holds its proof in memory,
+// so the memory used by a topic grows with the size. It is complementary to
+// WithBufferedSubscriptions, which buffers proofs per subscriber.
+func WithSubscriptionBufferSize[H header.Header[H]](size int) Option[H] {
+	return func(f *ProofService[H]) {
+		f.psSubBufferSize = size
+	}
+}
+
+// WithRawFallback enables storing proofs of types without an unmarshaler as fraud.RawProofs,
+// carrying only their type and bytes. Raw proofs can't be validated, so they are accepted only
+// from Ingest and local broadcasts, and are kept for serving and re-broadcasting them to peers,
+// e.g. by archival or relay nodes. Disabled by default.
+func WithRawFallback[H header.Header[H]](enabled bool) Option[H] {
+	return func(f *ProofService[H]) {
+		f.rawFallback = enabled
+	}
+}
+
+// WithMaxProofTypes caps the amount of distinct ProofTypes the ProofService ever registers,
+// including the ones of the ProofUnmarshaler registered on Start, bounding the topics and stores
+// it keeps. Registering further ProofTypes fails with ErrTooManyProofTypes, while re-registering
+// the already registered ones is allowed. Unlimited by default.
+func WithMaxProofTypes[H header.Header[H]](max int) Option[H] {
+	return func(f *ProofService[H]) {
+		f.maxProofTypes = max
+	}
+}
+
+// WithMinPeerConnAge ignores proofs from peers connected for less than the given age,
+// as brand-new connections are more likely to be sybils flooding proofs. The proofs are
+// ignored rather than rejected, so they can be retried once the connection matures.
+// The ages are measured with the Clock set with WithClock, from the Start of the ProofService
+// for the peers connected already. Disabled by default.
+func WithMinPeerConnAge[H header.Header[H]](age time.Duration) Option[H] {
+	return func(f *ProofService[H]) {
+		f.minPeerConnAge = age
+	}
+}
+
+// WithBatchVerifier sets the BatchVerifier of the given ProofType, used instead of its Verifier.
+// Proofs of the ProofType received within the window are coalesced and verified together,
+// so that expensive setup, e.g. loading a trusted state, is shared between them.
+// Every proof waits up to the window before being verified.
+func WithBatchVerifier[H header.Header[H]](
+	proofType fraud.ProofType,
+	verifier fraud.BatchVerifier[H],
+	window time.Duration,
+) Option[H] {
+	return func(f *ProofService[H]) {
+		if f.batchVerifiers == nil {
+			f.batchVerifiers = make(map[fraud.ProofType]*batchVerifier[H])
+		}
+		f.batchVerifiers[proofType] = &batchVerifier[H]{verify: verifier, window: window}
+	}
+}
+
+// WithTopicScore sets the peer score parameters of the topic of the given ProofType,
+// reducing the impact of spammy peers on adversarial networks. It requires the PubSub
+// to be a GossipSub with peer scoring enabled, otherwise joining the topic fails.
+// Topics are not scored by default.
+func WithTopicScore[H header.Header[H]](proofType fraud.ProofType, params *pubsub.TopicScoreParams) Option[H] {
+	return func(f *ProofService[H]) {
+		if f.topicScores == nil {
+			f.topicScores = make(map[fraud.ProofType]*pubsub.TopicScoreParams)
+		}
+		f.topicScores[proofType] = params
+	}
+}
+
+// WithPrioritizedValidation validates proofs inline in the validation workers of the PubSub
+// instead of asynchronously, bypassing the validation throttle shared by all the topics,
+// so that proofs aren't dropped while heavy data gossip exhausts it.
+// NOTE: A proof holds up a validation worker of the PubSub while it is validated, including fetching
+// its header. The workers are shared by all the topics of the PubSub, so slow header fetches stall
+// the validation of the messages of the other topics as well. The option suits HeaderFetchers
+// answering promptly.
+func WithPrioritizedValidation[H header.Header[H]]() Option[H] {
+	return func(f *ProofService[H]) {
+		f.inlineValidation = true
+	}
+}
+
+// WithMaxTotalProofs limits the total amount of stored proofs of all types, as a backstop against
+// disk exhaustion independent of the quotas set with WithTypeQuota. Once the limit is reached,
+// new proofs are ignored and counted by the fraud_store_full_refused metric, while stored proofs are retained.
+func WithMaxTotalProofs[H header.Header[H]](maxProofs int) Option[H] {
+	return func(f *ProofService[H]) {
+		f.maxTotalProofs = maxProofs
+	}
+}
+
+// WithTracerProvider makes the ProofService record its spans with the given TracerProvider
+// instead of the global one, e.g. for isolated tracing when embedded.
+func WithTracerProvider[H header.Header[H]](provider trace.TracerProvider) Option[H] {
+	return func(f *ProofService[H]) {
+		f.tracer = provider.Tracer(tracerName)
+	}
+}
+
+// WithBlacklist gives the ProofService the Blacklist passed to its PubSub with pubsub.WithBlacklist,
+// allowing to reverse the bans of peers with ProofService.Unblacklist.
+func WithBlacklist[H header.Header[H]](blacklist *Blacklist) Option[H] {
+	return func(f *ProofService[H]) {
+		f.blacklist = blacklist
+	}
+}
+
+// WithServeDisabled stops the ProofService from serving stored proofs to requesting peers,
+// which it does by default regardless of whether the syncer is enabled.
+func WithServeDisabled[H header.Header[H]]() Option[H] {
+	return func(f *ProofService[H]) {
+		f.serveDisabled = true
+	}
+}
+
+// WithServeMaxBytes caps the total size of the proofs served to a requesting peer in a response,
+// protecting the ProofService from peers pulling the whole store at once. Proofs over the cap
+// are left for the requesting peer to continue the response with, while a response always carries
+// at least one proof. The size is not capped by default.
+func WithServeMaxBytes[H header.Header[H]](maxBytes int) Option[H] {
+	return func(f *ProofService[H]) {
+		f.serveMaxBytes = maxBytes
+	}
+}
+
+// WithQueryBatchSize makes Get read the stored proofs in batches of the given size,
+// bounding the amount of proofs the datastore reads at once. All proofs are read at once by default.
+func WithQueryBatchSize[H header.Header[H]](size int) Option[H] {
+	return func(f *ProofService[H]) {
+		f.queryBatchSize = size
+	}
+}
+
+// WithSplitStore makes the ProofService read proofs from readDS, e.g. a replica, in Get, Known
+// and when checking whether incoming proofs are known, while storing them into writeDS,
+// overriding the provided datastore. The rest of the data, like the quotas bookkeeping,
+// is kept in writeDS. As the read datastore may lag behind, see WithSplitStoreFallback.
+func WithSplitStore[H header.Header[H]](readDS, writeDS datastore.Datastore) Option[H] {
+	return func(f *ProofService[H]) {
+		f.readDS, f.ds = readDS, writeDS
+	}
+}
+
+// WithSplitStoreFallback makes the ProofService look up incoming proofs missing from the read
+// datastore set with WithSplitStore in the write datastore as well, so that proofs just written
+// but not yet replicated are not processed again.
+func WithSplitStoreFallback[H header.Header[H]]() Option[H] {
+	return func(f *ProofService[H]) {
+		f.readFallback = true
+	}
+}
+
+// WithPipeline sets the stages processing the proofs received from the network, in order,
+// after the checks of their senders. It allows reordering the stages of DefaultPipeline,
+// e.g. to run a cheap check before fetching the header, and adding custom ones.
+// Proofs accepted by all the stages are delivered to the local subscriptions.
+// Ingest and DryRun run the stages as well, except for the ones specific to the messages,
+// see DefaultPipeline.
+func WithPipeline[H header.Header[H]](stages ...Stage[H]) Option[H] {
+	return func(f *ProofService[H]) {
+		f.pipeline = stages
+	}
+}
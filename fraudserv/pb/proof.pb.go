@@ -24,7 +24,10 @@ var _ = math.Inf
 const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
 
 type FraudMessageRequest struct {
-	RequestedProofType []string `protobuf:"bytes,1,rep,name=RequestedProofType,proto3" json:"RequestedProofType,omitempty"`
+	RequestedProofType []string         `protobuf:"bytes,1,rep,name=RequestedProofType,proto3" json:"RequestedProofType,omitempty"`
+	Pushed             []*ProofResponse `protobuf:"bytes,2,rep,name=Pushed,proto3" json:"Pushed,omitempty"`
+	RequestedHashes    []*ProofResponse `protobuf:"bytes,3,rep,name=RequestedHashes,proto3" json:"RequestedHashes,omitempty"`
+	Cursor             *ProofResponse   `protobuf:"bytes,4,opt,name=Cursor,proto3" json:"Cursor,omitempty"`
 }
 
 func (m *FraudMessageRequest) Reset()         { *m = FraudMessageRequest{} }
@@ -67,6 +70,27 @@ func (m *FraudMessageRequest) GetRequestedProofType() []string {
 	return nil
 }
 
+func (m *FraudMessageRequest) GetPushed() []*ProofResponse {
+	if m != nil {
+		return m.Pushed
+	}
+	return nil
+}
+
+func (m *FraudMessageRequest) GetRequestedHashes() []*ProofResponse {
+	if m != nil {
+		return m.RequestedHashes
+	}
+	return nil
+}
+
+func (m *FraudMessageRequest) GetCursor() *ProofResponse {
+	if m != nil {
+		return m.Cursor
+	}
+	return nil
+}
+
 type ProofResponse struct {
 	Type  string   `protobuf:"bytes,1,opt,name=Type,proto3" json:"Type,omitempty"`
 	Value [][]byte `protobuf:"bytes,2,rep,name=Value,proto3" json:"Value,omitempty"`
@@ -121,6 +145,7 @@ func (m *ProofResponse) GetValue() [][]byte {
 
 type FraudMessageResponse struct {
 	Proofs []*ProofResponse `protobuf:"bytes,1,rep,name=Proofs,proto3" json:"Proofs,omitempty"`
+	Cursor *ProofResponse   `protobuf:"bytes,2,opt,name=Cursor,proto3" json:"Cursor,omitempty"`
 }
 
 func (m *FraudMessageResponse) Reset()         { *m = FraudMessageResponse{} }
@@ -163,6 +188,13 @@ func (m *FraudMessageResponse) GetProofs() []*ProofResponse {
 	return nil
 }
 
+func (m *FraudMessageResponse) GetCursor() *ProofResponse {
+	if m != nil {
+		return m.Cursor
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*FraudMessageRequest)(nil), "fraud.pb.FraudMessageRequest")
 	proto.RegisterType((*ProofResponse)(nil), "fraud.pb.ProofResponse")
@@ -172,20 +204,24 @@ func init() {
 func init() { proto.RegisterFile("libs/fraud/pb/proof.proto", fileDescriptor_8ed4b0aa9157349f) }
 
 var fileDescriptor_8ed4b0aa9157349f = []byte{
-	// 208 bytes of a gzipped FileDescriptorProto
+	// 263 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x92, 0xcc, 0xc9, 0x4c, 0x2a,
 	0xd6, 0x4f, 0x2b, 0x4a, 0x2c, 0x4d, 0xd1, 0x2f, 0x48, 0xd2, 0x2f, 0x28, 0xca, 0xcf, 0x4f, 0xd3,
-	0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x00, 0x8b, 0xea, 0x15, 0x24, 0x29, 0xb9, 0x72, 0x09,
-	0xbb, 0x81, 0xd8, 0xbe, 0xa9, 0xc5, 0xc5, 0x89, 0xe9, 0xa9, 0x41, 0xa9, 0x85, 0xa5, 0xa9, 0xc5,
-	0x25, 0x42, 0x7a, 0x5c, 0x42, 0x50, 0x66, 0x6a, 0x4a, 0x00, 0x48, 0x63, 0x48, 0x65, 0x41, 0xaa,
-	0x04, 0xa3, 0x02, 0xb3, 0x06, 0x67, 0x10, 0x16, 0x19, 0x25, 0x4b, 0x2e, 0x5e, 0x30, 0x27, 0x28,
-	0xb5, 0xb8, 0x20, 0x3f, 0xaf, 0x38, 0x55, 0x48, 0x88, 0x8b, 0x05, 0xaa, 0x85, 0x51, 0x83, 0x33,
-	0x08, 0xcc, 0x16, 0x12, 0xe1, 0x62, 0x0d, 0x4b, 0xcc, 0x29, 0x4d, 0x95, 0x60, 0x52, 0x60, 0xd6,
-	0xe0, 0x09, 0x82, 0x70, 0x94, 0xdc, 0xb9, 0x44, 0x50, 0x5d, 0x00, 0x35, 0x41, 0x9f, 0x8b, 0x0d,
-	0x6c, 0x64, 0x31, 0xd8, 0x5a, 0x6e, 0x23, 0x71, 0x3d, 0x98, 0xa3, 0xf5, 0x50, 0xac, 0x0a, 0x82,
-	0x2a, 0x73, 0x92, 0x38, 0xf1, 0x48, 0x8e, 0xf1, 0xc2, 0x23, 0x39, 0xc6, 0x07, 0x8f, 0xe4, 0x18,
-	0x27, 0x3c, 0x96, 0x63, 0xb8, 0xf0, 0x58, 0x8e, 0xe1, 0xc6, 0x63, 0x39, 0x86, 0x24, 0x36, 0xb0,
-	0xaf, 0x8d, 0x01, 0x01, 0x00, 0x00, 0xff, 0xff, 0xf7, 0x45, 0x72, 0xa0, 0x12, 0x01, 0x00, 0x00,
+	0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x00, 0x8b, 0xea, 0x15, 0x24, 0x29, 0xbd, 0x62, 0xe4,
+	0x12, 0x76, 0x03, 0x71, 0x7c, 0x53, 0x8b, 0x8b, 0x13, 0xd3, 0x53, 0x83, 0x52, 0x0b, 0x4b, 0x53,
+	0x8b, 0x4b, 0x84, 0xf4, 0xb8, 0x84, 0xa0, 0xcc, 0xd4, 0x94, 0x00, 0x90, 0xce, 0x90, 0xca, 0x82,
+	0x54, 0x09, 0x46, 0x05, 0x66, 0x0d, 0xce, 0x20, 0x2c, 0x32, 0x42, 0xfa, 0x5c, 0x6c, 0x01, 0xa5,
+	0xc5, 0x19, 0xa9, 0x29, 0x12, 0x4c, 0x0a, 0xcc, 0x1a, 0xdc, 0x46, 0xe2, 0x7a, 0x30, 0x2b, 0xf4,
+	0xc0, 0x8a, 0x82, 0x52, 0x8b, 0x0b, 0xf2, 0xf3, 0x8a, 0x53, 0x83, 0xa0, 0xca, 0x84, 0x1c, 0xb9,
+	0xf8, 0xe1, 0xc6, 0x78, 0x24, 0x16, 0x67, 0xa4, 0x16, 0x4b, 0x30, 0xe3, 0xd7, 0x89, 0xae, 0x1e,
+	0x64, 0xa7, 0x73, 0x69, 0x51, 0x71, 0x7e, 0x91, 0x04, 0x8b, 0x02, 0x23, 0x5e, 0x3b, 0x21, 0xca,
+	0x94, 0x2c, 0xb9, 0x78, 0x51, 0x24, 0x84, 0x84, 0xb8, 0x58, 0xa0, 0xfe, 0x62, 0xd4, 0xe0, 0x0c,
+	0x02, 0xb3, 0x85, 0x44, 0xb8, 0x58, 0xc3, 0x12, 0x73, 0x4a, 0x53, 0xc1, 0x1e, 0xe1, 0x09, 0x82,
+	0x70, 0x94, 0x2a, 0xb8, 0x44, 0x50, 0x83, 0x09, 0x6a, 0x02, 0xc8, 0xdf, 0x20, 0x23, 0x8b, 0xc1,
+	0x61, 0x83, 0xd7, 0xdf, 0x60, 0x65, 0x48, 0x8e, 0x66, 0x22, 0xca, 0xd1, 0x4e, 0x12, 0x27, 0x1e,
+	0xc9, 0x31, 0x5e, 0x78, 0x24, 0xc7, 0xf8, 0xe0, 0x91, 0x1c, 0xe3, 0x84, 0xc7, 0x72, 0x0c, 0x17,
+	0x1e, 0xcb, 0x31, 0xdc, 0x78, 0x2c, 0xc7, 0x90, 0xc4, 0x06, 0x8e, 0x4c, 0x63, 0xc0, 0x00, 0x91,
+	0x55, 0xd4, 0xef, 0xe9, 0x01, 0x00, 0x00,
 }
 
 func (m *FraudMessageRequest) Marshal() (dAtA []byte, err error) {
@@ -208,6 +244,46 @@ func (m *FraudMessageRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Cursor != nil {
+		{
+			size, err := m.Cursor.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintProof(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x22
+	}
+	if len(m.RequestedHashes) > 0 {
+		for iNdEx := len(m.RequestedHashes) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.RequestedHashes[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintProof(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if len(m.Pushed) > 0 {
+		for iNdEx := len(m.Pushed) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Pushed[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintProof(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
+	}
 	if len(m.RequestedProofType) > 0 {
 		for iNdEx := len(m.RequestedProofType) - 1; iNdEx >= 0; iNdEx-- {
 			i -= len(m.RequestedProofType[iNdEx])
@@ -279,6 +355,18 @@ func (m *FraudMessageResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Cursor != nil {
+		{
+			size, err := m.Cursor.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintProof(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
 	if len(m.Proofs) > 0 {
 		for iNdEx := len(m.Proofs) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -319,6 +407,22 @@ func (m *FraudMessageRequest) Size() (n int) {
 			n += 1 + l + sovProof(uint64(l))
 		}
 	}
+	if len(m.Pushed) > 0 {
+		for _, e := range m.Pushed {
+			l = e.Size()
+			n += 1 + l + sovProof(uint64(l))
+		}
+	}
+	if len(m.RequestedHashes) > 0 {
+		for _, e := range m.RequestedHashes {
+			l = e.Size()
+			n += 1 + l + sovProof(uint64(l))
+		}
+	}
+	if m.Cursor != nil {
+		l = m.Cursor.Size()
+		n += 1 + l + sovProof(uint64(l))
+	}
 	return n
 }
 
@@ -353,6 +457,10 @@ func (m *FraudMessageResponse) Size() (n int) {
 			n += 1 + l + sovProof(uint64(l))
 		}
 	}
+	if m.Cursor != nil {
+		l = m.Cursor.Size()
+		n += 1 + l + sovProof(uint64(l))
+	}
 	return n
 }
 
@@ -423,6 +531,110 @@ func (m *FraudMessageRequest) Unmarshal(dAtA []byte) error {
 			}
 			m.RequestedProofType = append(m.RequestedProofType, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Pushed", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowProof
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthProof
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthProof
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Pushed = append(m.Pushed, &ProofResponse{})
+			if err := m.Pushed[len(m.Pushed)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RequestedHashes", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowProof
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthProof
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthProof
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.RequestedHashes = append(m.RequestedHashes, &ProofResponse{})
+			if err := m.RequestedHashes[len(m.RequestedHashes)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cursor", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowProof
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthProof
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthProof
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Cursor == nil {
+				m.Cursor = &ProofResponse{}
+			}
+			if err := m.Cursor.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipProof(dAtA[iNdEx:])
@@ -621,6 +833,42 @@ func (m *FraudMessageResponse) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cursor", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowProof
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthProof
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthProof
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Cursor == nil {
+				m.Cursor = &ProofResponse{}
+			}
+			if err := m.Cursor.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipProof(dAtA[iNdEx:])
@@ -0,0 +1,351 @@
+package fraudserv
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/celestiaorg/go-header"
+
+	"github.com/celestiaorg/go-fraud"
+)
+
+// Names of the stages of the default pipeline processing incoming proofs.
+const (
+	StageUnmarshal   = "unmarshal"
+	StageDedup       = "dedup"
+	StageKnown       = "known"
+	StageRaw         = "raw"
+	StageThreshold   = "threshold"
+	StageFetchHeader = "fetch_header"
+	StageVerifier    = "verifier"
+	StageValidate    = "validate"
+	StageStore       = "store"
+)
+
+// Incoming is a proof going through the pipeline of the ProofService.
+type Incoming[H header.Header[H]] struct {
+	// Type is the ProofType of the topic the proof is received on.
+	Type fraud.ProofType
+	// From is the peer the proof is received from. It is empty for Ingest and DryRun.
+	From peer.ID
+	// Message is the pubsub message carrying the proof. It is nil for Ingest and DryRun.
+	Message *pubsub.Message
+	// Proof is the unmarshalled proof, set by the unmarshal Stage.
+	Proof fraud.Proof[H]
+	// Header is the extended header of the proof, set by the fetch_header Stage.
+	Header H
+
+	serv    *ProofService[H]
+	fetched bool
+}
+
+// Stage is a step of the pipeline processing incoming proofs, see WithPipeline.
+type Stage[H header.Header[H]] struct {
+	// Name identifies the Stage in the pipeline.
+	Name string
+	// Run runs the Stage over the incoming proof. It returns pubsub.ValidationAccept to pass
+	// the proof on to the next Stage. Any other result ends the processing and is mapped by
+	// the result policy, while the reason and the error are logged.
+	// Failing with fraud.ErrBlacklistPeer blacklists the sender, as for verifiers,
+	// and failing with ErrFinalVerdict ends the processing with the result as is.
+	Run func(ctx context.Context, in *Incoming[H]) (pubsub.ValidationResult, ProcessReason, error)
+
+	// messageOnly marks the stages specific to the messages received from the network,
+	// which are skipped for Ingest and DryRun.
+	messageOnly bool
+}
+
+// ErrFinalVerdict is returned by a Stage, possibly wrapped, to end the processing of the proof
+// with the result returned along with it, skipping the remaining stages. An accepted proof is
+// delivered to the local subscriptions without being stored, unless stored by an earlier Stage,
+// while other results are not mapped by the result policy.
+var ErrFinalVerdict = errors.New("fraud: final verdict of the stage")
+
+// errDecided is returned by the stages of the default pipeline deciding on the proof themselves,
+// to end the processing with the returned result as is.
+var errDecided = errors.New("fraud: decided by the stage")
+
+// DefaultPipeline returns the stages processing incoming proofs by default, in order:
+// unmarshalling, deduplication, the check of locally known proofs, the handling of raw proofs,
+// the head threshold, the fetch of the header, the verifier, the validation against the header
+// and the storage. The stages depending on the Proof or its header must follow the stages setting them,
+// except for the validate Stage, fetching the header itself if needed.
+// Ingest and DryRun run the stages skipping the unmarshal, dedup, known, raw and store ones,
+// which are specific to the messages received from the network.
+func DefaultPipeline[H header.Header[H]]() []Stage[H] {
+	return []Stage[H]{
+		{Name: StageUnmarshal, Run: unmarshalStage[H], messageOnly: true},
+		{Name: StageDedup, Run: dedupStage[H], messageOnly: true},
+		{Name: StageKnown, Run: knownStage[H], messageOnly: true},
+		{Name: StageRaw, Run: rawStage[H], messageOnly: true},
+		{Name: StageThreshold, Run: thresholdStage[H]},
+		{Name: StageFetchHeader, Run: fetchHeaderStage[H]},
+		{Name: StageVerifier, Run: verifierStage[H]},
+		{Name: StageValidate, Run: validateStage[H]},
+		{Name: StageStore, Run: storeStage[H], messageOnly: true},
+	}
+}
+
+// runStages runs the stages of the pipeline over the proof until one of them doesn't accept it
+// or ends the processing otherwise. The stages specific to messages are skipped for proofs
+// without a message.
+func (f *ProofService[H]) runStages(
+	ctx context.Context,
+	in *Incoming[H],
+) (pubsub.ValidationResult, ProcessReason, error) {
+	for _, stage := range f.pipeline {
+		if stage.messageOnly && in.Message == nil {
+			continue
+		}
+		res, reason, err := stage.Run(ctx, in)
+		if res != pubsub.ValidationAccept || errors.Is(err, errDecided) || errors.Is(err, ErrFinalVerdict) {
+			return res, reason, err
+		}
+	}
+	return pubsub.ValidationAccept, "", nil
+}
+
+// runPipeline runs the pipeline of the ProofService over the incoming proof.
+func (f *ProofService[H]) runPipeline(ctx context.Context, in *Incoming[H]) pubsub.ValidationResult {
+	span := trace.SpanFromContext(ctx)
+	res, reason, err := f.runStages(ctx, in)
+	switch {
+	case errors.Is(err, errDecided):
+		return res
+	case res != pubsub.ValidationAccept:
+		return f.decline(ctx, in, res, reason, err)
+	}
+	if in.Proof == nil {
+		log.Errorw("pipeline passed a proof without unmarshalling it", "networkID", f.networkID,
+			"proofType", in.Type)
+		return pubsub.ValidationIgnore
+	}
+	// the store Stage sets it already, unless it is left out of the pipeline
+	if !f.setValidatorData(in.Message, in.Proof) {
+		return pubsub.ValidationIgnore
+	}
+	f.logDecision(in.Proof, pubsub.ValidationAccept, "", nil)
+
+	span.AddEvent("received_valid_proof", trace.WithAttributes(
+		attribute.String("proof_type", string(in.Proof.Type())),
+		attribute.Int("block_height", int(in.Proof.Height())),
+		attribute.String("block_hash", hex.EncodeToString(in.Proof.HeaderHash())),
+		attribute.String("from_peer", in.From.String()),
+	))
+	span.SetStatus(codes.Ok, "")
+	return pubsub.ValidationAccept
+}
+
+// decline ends the processing of the incoming proof not accepted by a Stage for the given reason.
+func (f *ProofService[H]) decline(
+	ctx context.Context,
+	in *Incoming[H],
+	res pubsub.ValidationResult,
+	reason ProcessReason,
+	err error,
+) pubsub.ValidationResult {
+	span := trace.SpanFromContext(ctx)
+	if !errors.Is(err, ErrFinalVerdict) {
+		res = f.resultFor(reason, res)
+	}
+	if in.Proof != nil {
+		f.logDecision(in.Proof, res, reason, err)
+	} else {
+		log.Errorw("fraud proof validation failed", "networkID", f.networkID, "proofType", in.Type,
+			"result", resultString(res), "reason", reason, "err", err)
+	}
+	span.SetAttributes(attribute.String("reason", string(reason)))
+	if res == pubsub.ValidationReject && err != nil {
+		span.RecordError(err)
+	}
+	// Peer will be added to black list if the validation of the proof itself fails
+	// or the stage requests it, regardless of the result policy.
+	if reason == ReasonInvalidProof && res == pubsub.ValidationReject {
+		f.blacklistPeer(in.From, reason)
+	} else if errors.Is(err, fraud.ErrBlacklistPeer) && in.From != f.host.ID() {
+		f.blacklistPeer(in.From, reason)
+	}
+	return res
+}
+
+// unmarshalStage unmarshals the message to the Proof, unless it is unmarshalled already.
+// Peer is handled according to the UnmarshalFailurePolicy if unmarshalling fails.
+func unmarshalStage[H header.Header[H]](
+	ctx context.Context,
+	in *Incoming[H],
+) (pubsub.ValidationResult, ProcessReason, error) {
+	f := in.serv
+	if in.Proof != nil {
+		return pubsub.ValidationAccept, "", nil
+	}
+	proof, err := f.unmarshal.Unmarshal(in.Type, in.Message.Data)
+	if err == nil {
+		in.Proof = proof
+		return pubsub.ValidationAccept, "", nil
+	}
+	trace.SpanFromContext(ctx).RecordError(err)
+	var errNoUnmarshaler *fraud.ErrNoUnmarshaler
+	if errors.As(err, &errNoUnmarshaler) {
+		f.unknownTypes.observe(ctx, in.Type, in.From)
+		return pubsub.ValidationReject, "", errDecided
+	}
+	log.Errorw("unmarshalling failed", "err", err, "networkID", f.networkID, "proofType", in.Type)
+	switch f.unmarshalFailurePolicy {
+	case UnmarshalFailureIgnore:
+		return pubsub.ValidationIgnore, "", errDecided
+	case UnmarshalFailureGraylist:
+		return pubsub.ValidationReject, "", errDecided
+	default:
+		f.blacklistPeer(in.From, ReasonUnmarshalFailed)
+		return pubsub.ValidationReject, "", errDecided
+	}
+}
+
+// dedupStage ignores the Proof seen recently, if WithDedupWindow is set.
+func dedupStage[H header.Header[H]](
+	ctx context.Context,
+	in *Incoming[H],
+) (pubsub.ValidationResult, ProcessReason, error) {
+	f := in.serv
+	if f.seen == nil {
+		return pubsub.ValidationAccept, "", nil
+	}
+	span := trace.SpanFromContext(ctx)
+	// dedup by the canonical form, so that differently encoded equal proofs are caught as well
+	canonical, err := fraud.CanonicalBytes(in.Proof)
+	if err != nil {
+		res := f.resultFor(ReasonNonCanonical, pubsub.ValidationReject)
+		f.logDecision(in.Proof, res, ReasonNonCanonical, err)
+		span.RecordError(err)
+		return res, "", errDecided
+	}
+	if f.seen.check(canonical) {
+		res := f.resultFor(ReasonDuplicate, pubsub.ValidationIgnore)
+		f.logDecision(in.Proof, res, ReasonDuplicate, nil)
+		span.AddEvent("received_duplicate_message")
+		return res, "", errDecided
+	}
+	return pubsub.ValidationAccept, "", nil
+}
+
+// knownStage checks the Proof locally and ignores it if it has been already stored locally.
+func knownStage[H header.Header[H]](
+	ctx context.Context,
+	in *Incoming[H],
+) (pubsub.ValidationResult, ProcessReason, error) {
+	f, proof, msg := in.serv, in.Proof, in.Message
+	if !f.verifyLocal(ctx, in.Type, f.keyHasher(proof.HeaderHash()), msg.Data) {
+		return pubsub.ValidationAccept, "", nil
+	}
+	// we are republishing our own known proof to the network, e.g. rebroadcasting it,
+	// so let it through, but mark it to avoid delivering it to the local subscriptions again.
+	if in.From == f.host.ID() && !msg.Local {
+		if !f.setValidatorData(msg, republished{}) {
+			return pubsub.ValidationIgnore, "", errDecided
+		}
+		f.logDecision(proof, pubsub.ValidationAccept, ReasonRepublished, nil)
+		return pubsub.ValidationAccept, "", errDecided
+	}
+	res := f.resultFor(ReasonKnown, pubsub.ValidationIgnore)
+	f.logDecision(proof, res, ReasonKnown, nil)
+	trace.SpanFromContext(ctx).AddEvent("received_known_fraud_proof", trace.WithAttributes(
+		attribute.String("proof_type", string(proof.Type())),
+		attribute.Int("block_height", int(proof.Height())),
+		attribute.String("block_hash", hex.EncodeToString(proof.HeaderHash())),
+		attribute.String("from_peer", in.From.String()),
+	))
+	return res, "", errDecided
+}
+
+// rawStage lets through only the raw proofs re-broadcast by us, as they can't be validated.
+func rawStage[H header.Header[H]](
+	ctx context.Context,
+	in *Incoming[H],
+) (pubsub.ValidationResult, ProcessReason, error) {
+	f, proof := in.serv, in.Proof
+	if _, ok := proof.(*fraud.RawProof[H]); !ok {
+		return pubsub.ValidationAccept, "", nil
+	}
+	if !in.Message.Local {
+		f.logDecision(proof, pubsub.ValidationIgnore, ReasonRaw, nil)
+		return pubsub.ValidationIgnore, "", errDecided
+	}
+	if !f.setValidatorData(in.Message, proof) {
+		return pubsub.ValidationIgnore, "", errDecided
+	}
+	if res, ok := f.putIncoming(ctx, trace.SpanFromContext(ctx), proof, in.Message.Data); !ok {
+		return res, "", errDecided
+	}
+	f.logDecision(proof, pubsub.ValidationAccept, ReasonRaw, nil)
+	return pubsub.ValidationAccept, "", errDecided
+}
+
+// thresholdStage checks the Proof against the network head, including its expiry.
+// Proofs fetched by the sync are ahead of our head while we are catching up,
+// so they are not checked against the head threshold.
+func thresholdStage[H header.Header[H]](
+	ctx context.Context,
+	in *Incoming[H],
+) (pubsub.ValidationResult, ProcessReason, error) {
+	synced := in.Message != nil && in.Message.Local && in.serv.isSyncing(in.Message.Data)
+	maxHeight, res, reason, err := in.serv.maxHeight(ctx, in.Proof, synced)
+	if err != nil {
+		return res, reason, err
+	}
+	return checkHeight(in.Proof, maxHeight)
+}
+
+// fetchHeaderStage fetches the extended header of the Proof.
+func fetchHeaderStage[H header.Header[H]](
+	ctx context.Context,
+	in *Incoming[H],
+) (pubsub.ValidationResult, ProcessReason, error) {
+	extHeader, res, reason, err := fetchHeader(ctx, in.Proof, in.serv.headerGetter)
+	if err != nil {
+		return res, reason, err
+	}
+	in.Header, in.fetched = extHeader, true
+	return pubsub.ValidationAccept, "", nil
+}
+
+// verifierStage runs the verifier of the ProofType, if any.
+func verifierStage[H header.Header[H]](
+	ctx context.Context,
+	in *Incoming[H],
+) (pubsub.ValidationResult, ProcessReason, error) {
+	return runVerifier(ctx, in.Proof, in.serv.activeVerifier(ctx, in.Proof.Type()))
+}
+
+// validateStage validates the Proof against its extended header, fetching it if needed.
+func validateStage[H header.Header[H]](
+	ctx context.Context,
+	in *Incoming[H],
+) (pubsub.ValidationResult, ProcessReason, error) {
+	if !in.fetched {
+		if res, reason, err := fetchHeaderStage(ctx, in); res != pubsub.ValidationAccept {
+			return res, reason, err
+		}
+	}
+	return validateProof(ctx, in.Proof, in.Header)
+}
+
+// storeStage adds the Proof to the storage.
+func storeStage[H header.Header[H]](
+	ctx context.Context,
+	in *Incoming[H],
+) (pubsub.ValidationResult, ProcessReason, error) {
+	if !in.serv.setValidatorData(in.Message, in.Proof) {
+		return pubsub.ValidationIgnore, "", errDecided
+	}
+	if res, ok := in.serv.putIncoming(ctx, trace.SpanFromContext(ctx), in.Proof, in.Message.Data); !ok {
+		return res, "", errDecided
+	}
+	return pubsub.ValidationAccept, "", nil
+}
@@ -0,0 +1,187 @@
+package fraudserv
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/go-header/headertest"
+
+	"github.com/celestiaorg/go-fraud"
+	"github.com/celestiaorg/go-fraud/fraudtest"
+)
+
+func TestService_PipelineReordered(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	// check the known proofs before the dedup and run the verifier before the header is fetched
+	// by the validate stage
+	stages := DefaultPipeline[*headertest.DummyHeader]()
+	reordered := []Stage[*headertest.DummyHeader]{
+		stages[0], stages[2], stages[1], stages[3], stages[4], stages[6], stages[7], stages[8],
+	}
+
+	proofs := []fraud.Proof[*headertest.DummyHeader]{
+		fraudtest.NewValidProofAt[*headertest.DummyHeader](1),
+		fraudtest.NewValidProofAt[*headertest.DummyHeader](1),
+		&fraudtest.DummyProof[*headertest.DummyHeader]{ProofHeight: 2},
+		// the header is not available yet
+		fraudtest.NewValidProofAt[*headertest.DummyHeader](11),
+		fraudtest.NewValidProofAt[*headertest.DummyHeader](1000),
+	}
+	expected := []pubsub.ValidationResult{
+		pubsub.ValidationAccept,
+		pubsub.ValidationIgnore,
+		pubsub.ValidationReject,
+		pubsub.ValidationIgnore,
+		pubsub.ValidationReject,
+	}
+	for _, opts := range [][]Option[*headertest.DummyHeader]{
+		nil,
+		{WithPipeline[*headertest.DummyHeader](reordered...)},
+	} {
+		serv := newTestService(ctx, t, false, opts...)
+		var verified atomic.Int32
+		verifier := func(fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+			verified.Add(1)
+			return true, nil
+		}
+		require.NoError(t, serv.AddVerifier(fraudtest.DummyProofType, verifier))
+		require.NoError(t, serv.Start(ctx))
+
+		for i, proof := range proofs {
+			msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, proof)}}
+			require.Equal(t, expected[i], serv.processIncoming(ctx, proof.Type(), "peer", msg), i)
+		}
+		// the verifier runs before the header is fetched when reordered
+		if opts == nil {
+			require.EqualValues(t, 2, verified.Load())
+		} else {
+			require.EqualValues(t, 3, verified.Load())
+		}
+		stored, err := serv.Get(ctx, fraudtest.DummyProofType)
+		require.NoError(t, err)
+		require.Len(t, stored, 1)
+		require.Len(t, serv.BlacklistedPeers(), 1)
+	}
+}
+
+func TestService_PipelineCustomStage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	// a cheap check rejecting the proofs at odd heights before fetching their headers
+	stages := DefaultPipeline[*headertest.DummyHeader]()
+	check := Stage[*headertest.DummyHeader]{
+		Name: "check_height",
+		Run: func(
+			_ context.Context,
+			in *Incoming[*headertest.DummyHeader],
+		) (pubsub.ValidationResult, ProcessReason, error) {
+			if in.Proof.Height()%2 == 1 {
+				return pubsub.ValidationReject, "odd_height",
+					fmt.Errorf("odd height %d: %w", in.Proof.Height(), fraud.ErrBlacklistPeer)
+			}
+			return pubsub.ValidationAccept, "", nil
+		},
+	}
+	pipeline := append(stages[:5:5], check)
+	pipeline = append(pipeline, stages[5:]...)
+	serv := newTestService(ctx, t, false, WithPipeline[*headertest.DummyHeader](pipeline...))
+	getter := serv.headerGetter
+	var fetched atomic.Int32
+	serv.headerGetter = func(ctx context.Context, height uint64) (*headertest.DummyHeader, error) {
+		fetched.Add(1)
+		return getter(ctx, height)
+	}
+	require.NoError(t, serv.Start(ctx))
+
+	odd := fraudtest.NewValidProofAt[*headertest.DummyHeader](1)
+	msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, odd)}}
+	require.Equal(t, pubsub.ValidationReject, serv.processIncoming(ctx, odd.Type(), "odd", msg))
+	require.Zero(t, fetched.Load())
+	blacklisted := serv.BlacklistedPeers()
+	require.Len(t, blacklisted, 1)
+	require.Equal(t, ProcessReason("odd_height"), blacklisted[0].Reason)
+
+	even := fraudtest.NewValidProofAt[*headertest.DummyHeader](2)
+	msg = &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, even)}}
+	require.Equal(t, pubsub.ValidationAccept, serv.processIncoming(ctx, even.Type(), "even", msg))
+	require.EqualValues(t, 1, fetched.Load())
+	require.IsType(t, even, msg.ValidatorData)
+	stored, err := serv.Get(ctx, even.Type())
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+
+	// the custom stage runs for the proofs checked and ingested locally as well
+	status, err := serv.DryRun(ctx, odd)
+	require.ErrorIs(t, err, fraud.ErrBlacklistPeer)
+	require.Equal(t, ProcessRejected, status)
+	status, err = serv.Ingest(ctx, odd)
+	require.ErrorIs(t, err, fraud.ErrBlacklistPeer)
+	require.Equal(t, ProcessRejected, status)
+	require.EqualValues(t, 1, fetched.Load())
+	status, err = serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](4))
+	require.NoError(t, err)
+	require.Equal(t, ProcessAccepted, status)
+	require.EqualValues(t, 2, fetched.Load())
+}
+
+func TestService_PipelineFinalVerdict(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	// a trusted check accepting the proofs at height 1 and rejecting the ones at height 2 for good
+	stages := DefaultPipeline[*headertest.DummyHeader]()
+	trusted := Stage[*headertest.DummyHeader]{
+		Name: "trusted",
+		Run: func(
+			_ context.Context,
+			in *Incoming[*headertest.DummyHeader],
+		) (pubsub.ValidationResult, ProcessReason, error) {
+			switch in.Proof.Height() {
+			case 1:
+				return pubsub.ValidationAccept, "", ErrFinalVerdict
+			case 2:
+				return pubsub.ValidationReject, "untrusted", ErrFinalVerdict
+			}
+			return pubsub.ValidationAccept, "", nil
+		},
+	}
+	pipeline := append(stages[:3:3], trusted)
+	pipeline = append(pipeline, stages[3:]...)
+	serv := newTestService(ctx, t, false,
+		WithPipeline[*headertest.DummyHeader](pipeline...),
+		WithResultPolicy[*headertest.DummyHeader](func(ProcessReason) pubsub.ValidationResult {
+			return pubsub.ValidationIgnore
+		}))
+	require.NoError(t, serv.Start(ctx))
+
+	// accepted without validating or storing the proof
+	accepted := fraudtest.NewInvalidProof[*headertest.DummyHeader]()
+	accepted.ProofHeight = 1
+	msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, accepted)}}
+	require.Equal(t, pubsub.ValidationAccept, serv.processIncoming(ctx, accepted.Type(), "peer", msg))
+	require.IsType(t, accepted, msg.ValidatorData)
+	_, err := serv.Get(ctx, accepted.Type())
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+	status, err := serv.DryRun(ctx, accepted)
+	require.NoError(t, err)
+	require.Equal(t, ProcessAccepted, status)
+
+	// rejected regardless of the result policy
+	rejected := fraudtest.NewValidProofAt[*headertest.DummyHeader](2)
+	msg = &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, rejected)}}
+	require.Equal(t, pubsub.ValidationReject, serv.processIncoming(ctx, rejected.Type(), "peer", msg))
+	status, err = serv.DryRun(ctx, rejected)
+	require.ErrorIs(t, err, ErrFinalVerdict)
+	require.Equal(t, ProcessRejected, status)
+}
@@ -0,0 +1,155 @@
+package fraudserv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/celestiaorg/go-header"
+
+	"github.com/celestiaorg/go-fraud"
+	pb "github.com/celestiaorg/go-fraud/fraudserv/pb"
+)
+
+// BroadcastTo sends the Proof only to the given peers over the fraud protocol instead of gossiping it
+// to the whole mesh, e.g. for staged rollouts or debugging. The peers process the Proof as
+// a gossiped one and deliver it to their local subscriptions, without propagating it further.
+// Unlike Broadcast, the Proof is not processed locally.
+func (f *ProofService[H]) BroadcastTo(ctx context.Context, p fraud.Proof[H], peers ...peer.ID) (err error) {
+	ctx, span := f.tracer.Start(ctx, "broadcast_proof_to", trace.WithAttributes(
+		attribute.String("proof_type", string(p.Type())),
+		attribute.Int("block_height", int(p.Height())),
+		attribute.Int("peers", len(peers)),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetStatus(codes.Ok, "")
+		}
+		span.End()
+	}()
+
+	if f.pubsub == nil {
+		return ErrPubSubDisabled
+	}
+	if !f.running() {
+		return ErrServiceNotRunning
+	}
+	if err = f.checkExpiry(ctx, p); err != nil {
+		return err
+	}
+	bin, err := p.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	msg := &pb.FraudMessageRequest{
+		Pushed: []*pb.ProofResponse{{Type: p.Type().String(), Value: [][]byte{bin}}},
+	}
+	for _, pid := range peers {
+		if _, sendErr := f.request(ctx, protocolID(f.networkID), pid, msg); sendErr != nil {
+			err = errors.Join(err, fmt.Errorf("fraud: sending proof to %s: %w", pid, sendErr))
+		}
+	}
+	return err
+}
+
+const (
+	// maxPushedProofs bounds the amount of proofs a peer pushes in a single request.
+	maxPushedProofs = 16
+	// maxPeerPushes bounds the amount of pushes of a peer processed concurrently.
+	maxPeerPushes = 2
+)
+
+// handlePushed processes the proofs pushed by the peer, refusing pushes over maxPushedProofs
+// or over maxPeerPushes processed concurrently. As pushed proofs bypass the throttling and
+// the scoring of pubsub, the peer is blacklisted if any of them is rejected.
+func (f *ProofService[H]) handlePushed(pid peer.ID, pushed []*pb.ProofResponse) error {
+	amount := 0
+	for _, resp := range pushed {
+		amount += len(resp.Value)
+	}
+	if amount > maxPushedProofs {
+		return fmt.Errorf("fraud: %d pushed proofs over the max of %d", amount, maxPushedProofs)
+	}
+
+	f.pushesLk.Lock()
+	if f.pushes[pid] >= maxPeerPushes {
+		f.pushesLk.Unlock()
+		return fmt.Errorf("fraud: over %d concurrent pushes of the peer", maxPeerPushes)
+	}
+	f.pushes[pid]++
+	f.pushesLk.Unlock()
+	defer func() {
+		f.pushesLk.Lock()
+		defer f.pushesLk.Unlock()
+		if f.pushes[pid]--; f.pushes[pid] == 0 {
+			delete(f.pushes, pid)
+		}
+	}()
+
+	var proofs []received[H]
+	for _, resp := range pushed {
+		for _, val := range resp.Value {
+			proofs = append(proofs, received[H]{proofType: fraud.ProofType(resp.Type), data: val})
+		}
+	}
+	if f.processFromPeer(pid, proofs) > 0 {
+		f.blacklistPeer(pid, ReasonRejectedPush)
+	}
+	return nil
+}
+
+// received is a proof received from a peer outside of gossip, with the Proof unmarshalled
+// from its data already, if not nil.
+type received[H header.Header[H]] struct {
+	proofType fraud.ProofType
+	data      []byte
+	proof     fraud.Proof[H]
+}
+
+// processFromPeer processes the proofs received from the peer outside of gossip, i.e. pushed by it
+// or pulled from it on reconciliation, as gossiped ones received from it, delivering the accepted ones
+// to the local subscriptions. It returns the amount of the rejected proofs.
+func (f *ProofService[H]) processFromPeer(pid peer.ID, proofs []received[H]) (rejected int) {
+	if f.pubsub == nil {
+		log.Debugw("ignoring proofs from peer in storage-only mode", "peer", pid)
+		return 0
+	}
+	if f.isBlacklisted(pid) {
+		log.Debugw("ignoring proofs from blacklisted peer", "peer", pid)
+		return 0
+	}
+	for _, proof := range proofs {
+		topic := PubsubTopicID(proof.proofType.String(), f.networkID)
+		msg := &pubsub.Message{
+			Message:      &pubsub_pb.Message{Data: proof.data, From: []byte(pid), Topic: &topic},
+			ReceivedFrom: pid,
+		}
+		switch f.processDecoded(f.ctx, proof.proofType, pid, msg, proof.proof) {
+		case pubsub.ValidationAccept:
+			f.deliverLocal(proof.proofType, msg)
+		case pubsub.ValidationReject:
+			rejected++
+		}
+	}
+	return rejected
+}
+
+// deliverLocal delivers the accepted message to the local subscriptions of the ProofType, if any.
+func (f *ProofService[H]) deliverLocal(proofType fraud.ProofType, msg *pubsub.Message) {
+	f.subsLk.Lock()
+	fo, ok := f.subs[proofType]
+	f.subsLk.Unlock()
+	if ok {
+		fo.deliver(msg)
+	}
+}
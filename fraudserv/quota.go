@@ -0,0 +1,265 @@
+package fraudserv
+
+import (
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	q "github.com/ipfs/go-datastore/query"
+
+	"github.com/celestiaorg/go-fraud"
+)
+
+// firstSeenSuffix is appended to the store root to get the root of first-seen timestamps,
+// so that they are never mixed with the proofs in queries.
+const firstSeenSuffix = "-firstseen"
+
+// firstSeenStore returns the store of first-seen timestamps of the proofs of the given type.
+func (f *ProofService[H]) firstSeenStore(proofType fraud.ProofType) datastore.Datastore {
+	return namespace.Wrap(f.ds, makeKey(f.storeNamespace+firstSeenSuffix, proofType))
+}
+
+// oldestFirstSeen returns when the oldest stored proof of every ProofType was first seen.
+// Only proofs stored with a quota have first-seen timestamps, so the other ProofTypes are omitted.
+func (f *ProofService[H]) oldestFirstSeen(ctx context.Context) map[fraud.ProofType]time.Time {
+	oldest := make(map[fraud.ProofType]time.Time, len(f.typeQuotas))
+	for proofType := range f.typeQuotas {
+		entries, err := query(ctx, f.firstSeenStore(proofType), q.Query{})
+		if err != nil {
+			log.Errorw("querying first-seen timestamps", "err", err, "proofType", proofType)
+			continue
+		}
+		for _, entry := range entries {
+			nanos, err := parseFirstSeen(entry.Value)
+			if err != nil {
+				log.Errorw("skipping first-seen timestamp", "err", err, "proofType", proofType, "key", entry.Key)
+				continue
+			}
+			seenAt := time.Unix(0, int64(nanos))
+			if first, ok := oldest[proofType]; !ok || seenAt.Before(first) {
+				oldest[proofType] = seenAt
+			}
+		}
+	}
+	return oldest
+}
+
+// parseFirstSeen decodes the first-seen timestamp in nanoseconds stored as 8 big-endian bytes.
+func parseFirstSeen(value []byte) (uint64, error) {
+	if len(value) != 8 {
+		return 0, fmt.Errorf("fraud: malformed first-seen timestamp of %d bytes", len(value))
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
+// countProofs counts the stored proofs of all the types, including the ones no longer supported.
+// It must be called with totalLk held.
+func (f *ProofService[H]) countProofs(ctx context.Context) error {
+	store := namespace.Wrap(f.ds, datastore.NewKey(f.storeNamespace))
+	entries, err := query(ctx, store, q.Query{KeysOnly: true})
+	if err != nil {
+		return err
+	}
+	f.totalProofs, f.totalCounted = len(entries), true
+	return nil
+}
+
+// moveFirstSeen moves the first-seen timestamp of the proof under the old key to the new one,
+// or deletes it unless kept, so that no timestamp outlives its proof.
+func moveFirstSeen(
+	ctx context.Context,
+	firstSeen datastore.Datastore,
+	oldKey, newKey datastore.Key,
+	keep bool,
+) error {
+	seenAt, err := firstSeen.Get(ctx, oldKey)
+	switch {
+	case errors.Is(err, datastore.ErrNotFound):
+		return nil
+	case err != nil:
+		return err
+	}
+	if keep {
+		if err = firstSeen.Put(ctx, newKey, seenAt); err != nil {
+			return err
+		}
+	}
+	return firstSeen.Delete(ctx, oldKey)
+}
+
+// checkCapacity returns ErrStoreFull if the proof is not stored yet, while the store holds
+// maxTotalProofs already. Stored proofs are retained, which is reported with known.
+// It must be called with totalLk held.
+func (f *ProofService[H]) checkCapacity(
+	ctx context.Context,
+	proofType fraud.ProofType,
+	hash string,
+) (known bool, err error) {
+	known, err = f.storeFor(proofType).Has(ctx, datastore.NewKey(hash))
+	if err != nil || known {
+		return known, err
+	}
+	if !f.totalCounted {
+		if err = f.countProofs(ctx); err != nil {
+			return false, err
+		}
+	}
+	if f.totalProofs < f.maxTotalProofs {
+		return false, nil
+	}
+	log.Warnw("refusing to store proof, the store is full", "proofType", proofType, "stored", f.totalProofs)
+	f.storeFullMetrics.observeRefused(ctx, proofType)
+	return false, fmt.Errorf("%w: %d proofs stored", ErrStoreFull, f.totalProofs)
+}
+
+// lockStore blocks writing proofs while the store is modified otherwise, and has the stored proofs
+// counted and indexed again once unlocked with the returned func.
+func (f *ProofService[H]) lockStore() (unlock func()) {
+	f.totalLk.Lock()
+	f.quotaLk.Lock()
+	return func() {
+		f.totalCounted = false
+		f.seenIndexes = nil
+		f.quotaLk.Unlock()
+		f.totalLk.Unlock()
+	}
+}
+
+// putWithQuota stores the proof of the ProofType with a quota, recording when it was first seen,
+// and evicts the proofs seen first once the quota is exceeded, returning how many were evicted.
+func (f *ProofService[H]) putWithQuota(
+	ctx context.Context,
+	proofType fraud.ProofType,
+	quota int,
+	hash string,
+	data []byte,
+) (evicted int, err error) {
+	f.quotaLk.Lock()
+	defer f.quotaLk.Unlock()
+
+	store, firstSeen := f.storeFor(proofType), f.firstSeenStore(proofType)
+	idx, ok := f.seenIndexes[proofType]
+	if !ok {
+		if idx, err = loadSeenIndex(ctx, store, firstSeen); err != nil {
+			return 0, err
+		}
+		if f.seenIndexes == nil {
+			f.seenIndexes = make(map[fraud.ProofType]*seenIndex)
+		}
+		f.seenIndexes[proofType] = idx
+	}
+	defer func() {
+		if err != nil {
+			// the index may be out of sync with the store, so it is loaded again on the next put
+			delete(f.seenIndexes, proofType)
+		}
+	}()
+
+	key := datastore.NewKey(hash)
+	known, err := store.Has(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if err = put(ctx, store, hash, data); err != nil || known {
+		return 0, err
+	}
+	now := uint64(f.clock.Now().UnixNano())
+	seenAt := make([]byte, 8)
+	binary.BigEndian.PutUint64(seenAt, now)
+	if err = firstSeen.Put(ctx, key, seenAt); err != nil {
+		return 0, err
+	}
+	heap.Push(idx, firstSeenEntry{key: key.String(), seenAt: now})
+	return evictOldest(ctx, store, firstSeen, idx, quota)
+}
+
+// evictOldest deletes the proofs seen first from the store and the index until at most quota proofs are left.
+func evictOldest(
+	ctx context.Context,
+	store, firstSeen datastore.Datastore,
+	idx *seenIndex,
+	quota int,
+) (evicted int, err error) {
+	for idx.Len() > quota {
+		oldest := heap.Pop(idx).(firstSeenEntry)
+		key := datastore.NewKey(oldest.key)
+		if err = store.Delete(ctx, key); err != nil {
+			return evicted, err
+		}
+		evicted++
+		if err = firstSeen.Delete(ctx, key); err != nil && !errors.Is(err, datastore.ErrNotFound) {
+			return evicted, err
+		}
+		log.Debugw("evicted proof over the quota", "key", oldest.key)
+	}
+	return evicted, nil
+}
+
+// firstSeenEntry is the key of a stored proof with when it was first seen.
+type firstSeenEntry struct {
+	key    string
+	seenAt uint64
+}
+
+// seenIndex is a min-heap of the stored proofs of a ProofType with a quota by when they were first seen,
+// so that the oldest ones are evicted without querying the store on every put.
+type seenIndex []firstSeenEntry
+
+// loadSeenIndex indexes the proofs in the store by their first-seen timestamps. Proofs stored without
+// a valid one, e.g. before the quota was set, are indexed as the oldest.
+func loadSeenIndex(ctx context.Context, store, firstSeen datastore.Datastore) (*seenIndex, error) {
+	entries, err := query(ctx, store, q.Query{KeysOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	seen, err := query(ctx, firstSeen, q.Query{})
+	if err != nil {
+		return nil, err
+	}
+	seenAt := make(map[string]uint64, len(seen))
+	for _, entry := range seen {
+		nanos, err := parseFirstSeen(entry.Value)
+		if err != nil {
+			log.Errorw("skipping first-seen timestamp", "err", err, "key", entry.Key)
+			continue
+		}
+		seenAt[entry.Key] = nanos
+	}
+	idx := make(seenIndex, 0, len(entries))
+	for _, entry := range entries {
+		idx = append(idx, firstSeenEntry{key: entry.Key, seenAt: seenAt[entry.Key]})
+	}
+	heap.Init(&idx)
+	return &idx, nil
+}
+
+func (idx seenIndex) Len() int {
+	return len(idx)
+}
+
+func (idx seenIndex) Less(i, j int) bool {
+	if idx[i].seenAt != idx[j].seenAt {
+		return idx[i].seenAt < idx[j].seenAt
+	}
+	return idx[i].key < idx[j].key
+}
+
+func (idx seenIndex) Swap(i, j int) {
+	idx[i], idx[j] = idx[j], idx[i]
+}
+
+func (idx *seenIndex) Push(x any) {
+	*idx = append(*idx, x.(firstSeenEntry))
+}
+
+func (idx *seenIndex) Pop() any {
+	old := *idx
+	entry := old[len(old)-1]
+	*idx = old[:len(old)-1]
+	return entry
+}
@@ -0,0 +1,75 @@
+package fraudserv
+
+import (
+	"context"
+	"time"
+
+	q "github.com/ipfs/go-datastore/query"
+
+	"github.com/celestiaorg/go-fraud"
+)
+
+// rebroadcastLimit bounds the amount of proofs of a single type republished per interval
+// to avoid amplification storms.
+const rebroadcastLimit = 16
+
+// republished marks messages carrying our own known proofs republished to the network.
+type republished struct{}
+
+// rebroadcast periodically republishes locally stored proofs on their topics.
+func (f *ProofService[H]) rebroadcast(ctx context.Context) {
+	ticker := time.NewTicker(f.rebroadcastInterval)
+	defer ticker.Stop()
+	cursors := make(map[fraud.ProofType]string)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for proofType, topic := range f.joinedTopics() {
+			// nobody to rebroadcast to
+			if len(topic.ListPeers()) == 0 {
+				continue
+			}
+
+			entries, err := f.rebroadcastBatch(ctx, proofType, cursors)
+			if err != nil {
+				log.Errorw("querying proofs to rebroadcast", "err", err, "proofType", proofType)
+				continue
+			}
+			for _, entry := range entries {
+				err = topic.Publish(ctx, entry.Value)
+				if err != nil {
+					log.Debugw("rebroadcasting proof", "err", err, "proofType", proofType)
+				}
+			}
+		}
+	}
+}
+
+// rebroadcastBatch returns the next batch of the stored proofs of the ProofType to rebroadcast.
+// The cursors keep the key of the last proof rebroadcast per type, so that all the stored proofs
+// are rebroadcast in turns, starting over once the last one is reached.
+func (f *ProofService[H]) rebroadcastBatch(
+	ctx context.Context,
+	proofType fraud.ProofType,
+	cursors map[fraud.ProofType]string,
+) ([]q.Entry, error) {
+	store, after := f.storeFor(proofType), cursors[proofType]
+	entries, err := queryAfter(ctx, store, q.Query{}, after, rebroadcastLimit)
+	if err == nil && len(entries) == 0 && after != "" {
+		// the last proof was reached exactly with the previous batch
+		entries, err = queryAfter(ctx, store, q.Query{}, "", rebroadcastLimit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) < rebroadcastLimit {
+		delete(cursors, proofType)
+	} else {
+		cursors[proofType] = entries[len(entries)-1].Key
+	}
+	return entries, nil
+}
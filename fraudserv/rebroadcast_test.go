@@ -0,0 +1,71 @@
+package fraudserv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/go-header/headertest"
+
+	"github.com/celestiaorg/go-fraud"
+	"github.com/celestiaorg/go-fraud/fraudtest"
+)
+
+func TestService_PeriodicRebroadcast(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(2)
+	require.NoError(t, err)
+
+	servA := newTestServiceWithHost(ctx, t, net.Hosts()[0], false,
+		WithPeriodicRebroadcast[*headertest.DummyHeader](time.Millisecond*100))
+	require.NoError(t, servA.Start(ctx))
+
+	// servA already knows the proof before servB joins the network
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	err = servA.put(ctx, proof.Type(), servA.keyHasher(proof.HeaderHash()), mustMarshal(t, proof))
+	require.NoError(t, err)
+
+	servB := newTestServiceWithHost(ctx, t, net.Hosts()[1], false)
+	require.NoError(t, servB.Start(ctx))
+	sub, err := servB.Subscribe(proof.Type())
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	addrB := host.InfoFromHost(net.Hosts()[1])
+	require.NoError(t, net.Hosts()[0].Connect(ctx, *addrB))
+
+	_, err = sub.Proof(ctx)
+	require.NoError(t, err)
+}
+
+func TestService_RebroadcastRotates(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+	const stored = rebroadcastLimit + rebroadcastLimit/2
+	for height := uint64(1); height <= stored; height++ {
+		proof := fraudtest.NewValidProofAt[*headertest.DummyHeader](height)
+		require.NoError(t, serv.put(ctx, proof.Type(), serv.keyHasher(proof.HeaderHash()), mustMarshal(t, proof)))
+	}
+
+	// every stored proof is rebroadcast in turns, starting over after the last one
+	cursors := make(map[fraud.ProofType]string)
+	rebroadcast := make(map[string]int)
+	for _, expected := range []int{rebroadcastLimit, rebroadcastLimit / 2, rebroadcastLimit} {
+		entries, err := serv.rebroadcastBatch(ctx, fraudtest.DummyProofType, cursors)
+		require.NoError(t, err)
+		require.Len(t, entries, expected)
+		for _, entry := range entries {
+			rebroadcast[entry.Key]++
+		}
+	}
+	require.Len(t, rebroadcast, stored)
+}
@@ -0,0 +1,155 @@
+package fraudserv
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+
+	"github.com/celestiaorg/go-fraud"
+	pb "github.com/celestiaorg/go-fraud/fraudserv/pb"
+)
+
+// inventoryProtocolID returns the ID of the protocol exchanging the inventories of stored proofs.
+// It reuses the messages of the fraud protocol, with every proof in the response encoded
+// as an inventory item instead.
+func inventoryProtocolID(networkID string) protocol.ID {
+	return protocol.ID(fmt.Sprintf("/%s/fraud-inventory/v0.0.1", networkID))
+}
+
+// inventoryItem encodes the compact identification of a proof:
+// its height as 8 big-endian bytes followed by its header hash.
+func inventoryItem(height uint64, hash []byte) []byte {
+	item := make([]byte, 8, 8+len(hash))
+	binary.BigEndian.PutUint64(item, height)
+	return append(item, hash...)
+}
+
+// parseInventoryItem decodes the inventory item into the height and the header hash of a proof.
+func parseInventoryItem(item []byte) (height uint64, hash []byte, err error) {
+	if len(item) <= 8 {
+		return 0, nil, errors.New("fraud: malformed inventory item")
+	}
+	return binary.BigEndian.Uint64(item[:8]), item[8:], nil
+}
+
+// handleInventoryRequest handles an incoming FraudMessageRequest for the inventory of proofs.
+func (f *ProofService[H]) handleInventoryRequest(stream network.Stream) {
+	req, ok := readRequest(stream)
+	if !ok {
+		return
+	}
+	f.respond(stream, req, func(proof fraud.Proof[H]) ([]byte, error) {
+		return inventoryItem(proof.Height(), proof.HeaderHash()), nil
+	})
+}
+
+// reconcileOnConnect exchanges the inventories of stored proofs with every newly identified peer
+// and pulls the proofs missing locally.
+func (f *ProofService[H]) reconcileOnConnect(ctx context.Context) {
+	sub, err := f.host.EventBus().Subscribe(&event.EvtPeerIdentificationCompleted{})
+	if err != nil {
+		log.Errorw("subscribing to identified peers for reconciliation", "err", err)
+		return
+	}
+	defer sub.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-sub.Out():
+			pid := e.(event.EvtPeerIdentificationCompleted).Peer
+			if pid == f.host.ID() {
+				continue
+			}
+			go func() {
+				if err := f.reconcile(ctx, pid); err != nil {
+					log.Debugw("reconciling proofs with peer", "err", err, "peer", pid)
+				}
+			}()
+		}
+	}
+}
+
+// reconcile requests the inventory of the peer and pulls the proofs missing locally
+// via the fraud protocol, processing them as if they were gossiped by the peer.
+func (f *ProofService[H]) reconcile(ctx context.Context, pid peer.ID) error {
+	proofTypes := make([]string, 0)
+	for _, proofType := range f.unmarshal.List() {
+		proofTypes = append(proofTypes, proofType.String())
+	}
+	inventory, err := f.requestProofs(ctx, inventoryProtocolID(f.networkID), pid, proofTypes)
+	if err != nil {
+		return fmt.Errorf("requesting inventory: %w", err)
+	}
+
+	// the header hashes of the missing proofs by their keys per ProofType
+	missing := make(map[fraud.ProofType]map[string][]byte)
+	for _, resp := range inventory {
+		proofType := fraud.ProofType(resp.Type)
+		for _, item := range resp.Value {
+			_, hash, err := parseInventoryItem(item)
+			if err != nil {
+				return err
+			}
+			key := f.keyHasher(hash)
+			_, err = getByHash(ctx, f.readStoreFor(proofType), key)
+			switch {
+			case err == nil:
+				continue
+			case !errors.Is(err, datastore.ErrNotFound):
+				return err
+			}
+			if missing[proofType] == nil {
+				missing[proofType] = make(map[string][]byte)
+			}
+			missing[proofType][key] = hash
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	// request only the missing proofs, while peers not narrowing the response to the hashes
+	// send all their proofs of the types
+	req := &pb.FraudMessageRequest{
+		RequestedProofType: make([]string, 0, len(missing)),
+		RequestedHashes:    make([]*pb.ProofResponse, 0, len(missing)),
+	}
+	for proofType, hashes := range missing {
+		requested := &pb.ProofResponse{Type: proofType.String(), Value: make([][]byte, 0, len(hashes))}
+		for _, hash := range hashes {
+			requested.Value = append(requested.Value, hash)
+		}
+		req.RequestedProofType = append(req.RequestedProofType, proofType.String())
+		req.RequestedHashes = append(req.RequestedHashes, requested)
+	}
+	log.Debugw("pulling missing proofs from peer", "peer", pid, "proofTypes", req.RequestedProofType)
+	resp, err := f.requestAll(ctx, protocolID(f.networkID), pid, req)
+	if err != nil {
+		return fmt.Errorf("requesting missing proofs: %w", err)
+	}
+	// the proofs are unmarshalled once to be filtered and passed on to the pipeline
+	var pulled []received[H]
+	for _, proofs := range resp {
+		proofType := fraud.ProofType(proofs.Type)
+		for _, val := range proofs.Value {
+			proof, err := f.unmarshal.Unmarshal(proofType, val)
+			if err != nil {
+				log.Debugw("unmarshalling pulled proof", "err", err, "peer", pid)
+				continue
+			}
+			if _, ok := missing[proofType][f.keyHasher(proof.HeaderHash())]; ok {
+				pulled = append(pulled, received[H]{proofType: proofType, data: val, proof: proof})
+			}
+		}
+	}
+	f.processFromPeer(pid, pulled)
+	return nil
+}
@@ -1,7 +1,11 @@
 package fraudserv
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -9,6 +13,7 @@ import (
 
 	"github.com/celestiaorg/go-libp2p-messenger/serde"
 
+	"github.com/celestiaorg/go-fraud"
 	pb "github.com/celestiaorg/go-fraud/fraudserv/pb"
 )
 
@@ -17,6 +22,10 @@ const (
 	writeDeadline = time.Second * 5
 	// readDeadline sets timeout for reading messages from the stream
 	readDeadline = time.Minute
+	// maxContinuations bounds the amount of capped responses continued by a single request
+	maxContinuations = 256
+	// maxRequestedBytes bounds the total size of the proofs received by a single request
+	maxRequestedBytes = 256 << 20
 )
 
 func (f *ProofService[H]) requestProofs(
@@ -25,7 +34,140 @@ func (f *ProofService[H]) requestProofs(
 	pid peer.ID,
 	proofTypes []string,
 ) ([]*pb.ProofResponse, error) {
-	msg := &pb.FraudMessageRequest{RequestedProofType: proofTypes}
+	return f.requestAll(ctx, id, pid, &pb.FraudMessageRequest{RequestedProofType: proofTypes})
+}
+
+// requestAll sends the FraudMessageRequest to the peer and continues the responses capped by the peer
+// with their Cursors until all the requested proofs are received. Every Cursor must move past the previous
+// one and the proofs received, while the continuations are bounded by maxContinuations and maxRequestedBytes.
+// Duplicate proofs are dropped.
+func (f *ProofService[H]) requestAll(
+	ctx context.Context,
+	id protocol.ID,
+	pid peer.ID,
+	msg *pb.FraudMessageRequest,
+) ([]*pb.ProofResponse, error) {
+	var proofs []*pb.ProofResponse
+	received := make(map[string]map[[sha256.Size]byte]struct{})
+	size := 0
+	for round := 0; ; round++ {
+		if round > maxContinuations {
+			return nil, fmt.Errorf("fraud: peer exceeded %d continuations of the response", maxContinuations)
+		}
+		resp, err := f.request(ctx, id, pid, msg)
+		if err != nil {
+			return nil, err
+		}
+		var last *pb.ProofResponse
+		for _, resProofs := range resp.Proofs {
+			last = resProofs
+			for _, val := range resProofs.Value {
+				size += len(val)
+			}
+			if size > maxRequestedBytes {
+				return nil, fmt.Errorf("fraud: peer exceeded %d bytes of the response", maxRequestedBytes)
+			}
+			if received[resProofs.Type] == nil {
+				received[resProofs.Type] = make(map[[sha256.Size]byte]struct{})
+			}
+			values := resProofs.Value[:0]
+			for _, val := range resProofs.Value {
+				hash := sha256.Sum256(val)
+				if _, ok := received[resProofs.Type][hash]; ok {
+					continue
+				}
+				received[resProofs.Type][hash] = struct{}{}
+				values = append(values, val)
+			}
+			resProofs.Value = values
+			// a continued response starts with the rest of the proofs of the type it was capped at
+			if prev := len(proofs) - 1; prev >= 0 && proofs[prev].Type == resProofs.Type {
+				proofs[prev].Value = append(proofs[prev].Value, resProofs.Value...)
+				continue
+			}
+			proofs = append(proofs, resProofs)
+		}
+		if resp.Cursor == nil {
+			return proofs, nil
+		}
+		if err = f.checkCursor(id, msg, resp.Cursor, last); err != nil {
+			return nil, err
+		}
+		msg.Cursor = resp.Cursor
+	}
+}
+
+// checkCursor ensures the Cursor of a capped response to the FraudMessageRequest moves past the Cursor
+// of the request, as well as past the last proofs of the response, so that continuing the response
+// always advances.
+func (f *ProofService[H]) checkCursor(
+	id protocol.ID,
+	msg *pb.FraudMessageRequest,
+	next *pb.ProofResponse,
+	last *pb.ProofResponse,
+) error {
+	typeIndex := func(proofType string) int {
+		for i, p := range msg.RequestedProofType {
+			if p == proofType {
+				return i
+			}
+		}
+		return -1
+	}
+	nextIdx := typeIndex(next.Type)
+	if nextIdx == -1 || len(next.Value) > 1 {
+		return errors.New("fraud: peer sent a malformed cursor")
+	}
+	var nextItem []byte
+	if len(next.Value) == 1 {
+		if _, _, err := parseInventoryItem(next.Value[0]); err != nil {
+			return fmt.Errorf("fraud: peer sent a malformed cursor: %w", err)
+		}
+		nextItem = next.Value[0]
+	}
+	// inventory items order the proofs of a type the same as their heights and header hashes
+	if prev := msg.Cursor; prev != nil {
+		prevIdx := typeIndex(prev.Type)
+		var prevItem []byte
+		if len(prev.Value) == 1 {
+			prevItem = prev.Value[0]
+		}
+		if nextIdx < prevIdx || (nextIdx == prevIdx && bytes.Compare(nextItem, prevItem) <= 0) {
+			return errors.New("fraud: peer sent a cursor not moving past the previous one")
+		}
+	}
+	if last != nil && last.Type == next.Type && len(last.Value) > 0 {
+		lastItem, err := f.responseItem(id, last.Type, last.Value[len(last.Value)-1])
+		if err != nil {
+			return err
+		}
+		if bytes.Compare(nextItem, lastItem) < 0 {
+			return errors.New("fraud: peer sent a cursor before the proofs it served")
+		}
+	}
+	return nil
+}
+
+// responseItem returns the inventory item of the proof value received over the protocol with the given ID.
+func (f *ProofService[H]) responseItem(id protocol.ID, proofType string, value []byte) ([]byte, error) {
+	if id == inventoryProtocolID(f.networkID) {
+		return value, nil
+	}
+	proof, err := f.unmarshal.Unmarshal(fraud.ProofType(proofType), value)
+	if err != nil {
+		return nil, err
+	}
+	return inventoryItem(proof.Height(), proof.HeaderHash()), nil
+}
+
+// request sends the FraudMessageRequest to the peer over the protocol with the given ID
+// and reads the response.
+func (f *ProofService[H]) request(
+	ctx context.Context,
+	id protocol.ID,
+	pid peer.ID,
+	msg *pb.FraudMessageRequest,
+) (*pb.FraudMessageResponse, error) {
 	stream, err := f.host.NewStream(ctx, pid, id)
 	if err != nil {
 		return nil, err
@@ -51,5 +193,5 @@ func (f *ProofService[H]) requestProofs(
 		stream.Reset() //nolint:errcheck
 		return nil, err
 	}
-	return resp.Proofs, stream.Close()
+	return resp, stream.Close()
 }
@@ -3,12 +3,20 @@ package fraudserv
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	q "github.com/ipfs/go-datastore/query"
 	logging "github.com/ipfs/go-log/v2"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/libp2p/go-libp2p/core/host"
@@ -16,6 +24,7 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/celestiaorg/go-header"
@@ -23,9 +32,31 @@ import (
 	"github.com/celestiaorg/go-fraud"
 )
 
+const tracerName = "fraudserv"
+
 var (
 	log    = logging.Logger("fraudserv")
-	tracer = otel.Tracer("fraudserv")
+	tracer = otel.Tracer(tracerName)
+)
+
+var (
+	// ErrTopicNotFound is returned when there is no joined topic for the requested ProofType.
+	ErrTopicNotFound = errors.New("fraud: topic not found")
+	// ErrServiceNotRunning is returned when the ProofService is used before Start or after Stop.
+	ErrServiceNotRunning = errors.New("fraud: service is not running")
+	// ErrServiceStopped is returned by Subscription.Proof once the ProofService is stopped.
+	ErrServiceStopped = errors.New("fraud: service stopped")
+	// ErrPubSubDisabled is returned by the gossip methods of a storage-only ProofService.
+	ErrPubSubDisabled = errors.New("fraud: pubsub is disabled in storage-only mode")
+	// ErrStoreUnwritable is reported by Health when writes to the store keep failing.
+	ErrStoreUnwritable = errors.New("fraud: store keeps failing writes")
+	// ErrProofExpired is returned when broadcasting a fraud.Expiring Proof past its expiry.
+	ErrProofExpired = errors.New("fraud: proof expired")
+	// ErrTooManyProofTypes is returned when registering more ProofTypes than set with WithMaxProofTypes.
+	ErrTooManyProofTypes = errors.New("fraud: too many proof types")
+	// ErrStoreFull is returned when storing a new Proof while the store holds the amount of proofs
+	// set with WithMaxTotalProofs.
+	ErrStoreFull = errors.New("fraud: store is full")
 )
 
 const (
@@ -36,6 +67,10 @@ const (
 	// headThreshold specifies the maximum allowable height of the Proof
 	// relative to the network head to be verified.
 	headThreshold uint64 = 20
+
+	// persistentWriteFailures is the amount of consecutive failed writes
+	// after which the store is considered to be persistently failing.
+	persistentWriteFailures = 3
 )
 
 // ProofService is responsible for validating and propagating Fraud Proofs.
@@ -49,12 +84,16 @@ type ProofService[H header.Header[H]] struct {
 	topicsLk sync.RWMutex
 	topics   map[fraud.ProofType]*pubsub.Topic
 
-	storesLk sync.RWMutex
-	stores   map[fraud.ProofType]datastore.Datastore
+	storesLk   sync.RWMutex
+	stores     map[fraud.ProofType]datastore.Datastore
+	readStores map[fraud.ProofType]datastore.Datastore
 
 	verifiersLk sync.RWMutex
 	verifiers   map[fraud.ProofType]fraud.Verifier[H]
 
+	subsLk sync.Mutex
+	subs   map[fraud.ProofType]*fanout[H]
+
 	pubsub        *pubsub.PubSub
 	host          host.Host
 	headerGetter  fraud.HeaderFetcher[H]
@@ -62,8 +101,94 @@ type ProofService[H header.Header[H]] struct {
 	unmarshal     fraud.ProofUnmarshaler[H]
 	ds            datastore.Datastore
 	syncerEnabled bool
+
+	remoteKnownCheck    bool
+	rebroadcastInterval time.Duration
+	encryptionKey       []byte
+	keyHasher           func([]byte) string
+	dedupWindow         time.Duration
+	dedupMaxSize        int
+	seen                *seenSet
+	seenMetrics         metric.Registration
+	ageMetrics          metric.Registration
+	blacklistMetrics    metric.Registration
+	msgIDFn             pubsub.MsgIdFunction
+	subBufferSize       int
+	skipHeadThreshold   bool
+	headThresholds      map[fraud.ProofType]uint64
+	syncProgress        func(fraud.ProofType, int)
+	subReplay           bool
+	storeNamespace      string
+	readOnlyStore       bool
+	clock               Clock
+	typeQuotas          map[fraud.ProofType]int
+	subMetrics          *subscriptionMetrics
+	syncMetrics         *syncMetrics
+
+	writesLk     sync.Mutex
+	failedWrites int
+	lastWriteErr error
+
+	blacklistLk sync.Mutex
+	blacklisted map[peer.ID]BlacklistEntry
+	blacklist   *Blacklist
+
+	// pushesLk guards pushes, the amount of pushes processed concurrently per peer.
+	pushesLk sync.Mutex
+	pushes   map[peer.ID]int
+
+	quotaLk sync.Mutex
+	// seenIndexes are the indexes of the stored proofs of the types with a quota, loaded on the first put.
+	seenIndexes map[fraud.ProofType]*seenIndex
+	// totalLk serializes the writes checked against maxTotalProofs.
+	totalLk sync.Mutex
+	// totalProofs is the number of stored proofs of all the types, valid if totalCounted.
+	totalProofs  int
+	totalCounted bool
+
+	unmarshalFailurePolicy UnmarshalFailurePolicy
+	unknownTypes           *unknownTypes
+	syncPeerSelector       func(context.Context) []peer.ID
+	syncWrites             bool
+	autoLeaveIdle          time.Duration
+	connectReconciliation  bool
+	resultPolicy           func(ProcessReason) pubsub.ValidationResult
+	psSubBufferSize        int
+	inlineValidation       bool
+	rawFallback            bool
+	maxProofTypes          int
+	minPeerConnAge         time.Duration
+	connAges               *connAges
+	stopConnAges           func()
+	batchVerifiers         map[fraud.ProofType]*batchVerifier[H]
+	topicScores            map[fraud.ProofType]*pubsub.TopicScoreParams
+	maxTotalProofs         int
+	tracer                 trace.Tracer
+	storeFullMetrics       *storeFullMetrics
+	serveDisabled          bool
+	serveMaxBytes          int
+	queryBatchSize         int
+	readDS                 datastore.Datastore
+	readFallback           bool
+	pipeline               []Stage[H]
+	// registered holds the ProofTypes ever joined, bounded by maxProofTypes, guarded by topicsLk.
+	registered map[fraud.ProofType]struct{}
+	// topicUsed and idleTopics are guarded by topicsLk and are only used with autoLeaveIdle.
+	topicUsed  map[fraud.ProofType]time.Time
+	idleTopics map[fraud.ProofType]struct{}
+
+	// syncing counts the proofs being published locally by the sync.
+	syncingLk sync.Mutex
+	syncing   map[[sha256.Size]byte]int
+	// broadcasting keeps the span contexts of the proofs being broadcast.
+	broadcastingLk sync.Mutex
+	broadcasting   map[[sha256.Size]byte]trace.SpanContext
 }
 
+// NewProofService creates a new ProofService.
+// Passing nil PubSub makes the ProofService storage-only: proofs are not gossiped and are
+// only added with Ingest, while Get and metrics keep working. Host may be nil as well then,
+// in which case proofs are not served to peers either.
 func NewProofService[H header.Header[H]](
 	p *pubsub.PubSub,
 	host host.Host,
@@ -73,95 +198,678 @@ func NewProofService[H header.Header[H]](
 	ds datastore.Datastore,
 	syncerEnabled bool,
 	networkID string,
+	opts ...Option[H],
 ) *ProofService[H] {
-	return &ProofService[H]{
-		pubsub:        p,
-		host:          host,
-		headerGetter:  headerGetter,
-		headGetter:    headGetter,
-		unmarshal:     unmarshal,
-		verifiers:     make(map[fraud.ProofType]fraud.Verifier[H]),
-		topics:        make(map[fraud.ProofType]*pubsub.Topic),
-		stores:        make(map[fraud.ProofType]datastore.Datastore),
-		ds:            ds,
-		networkID:     networkID,
-		syncerEnabled: syncerEnabled,
+	f := &ProofService[H]{
+		pubsub:         p,
+		host:           host,
+		headerGetter:   headerGetter,
+		headGetter:     headGetter,
+		unmarshal:      unmarshal,
+		verifiers:      make(map[fraud.ProofType]fraud.Verifier[H]),
+		topics:         make(map[fraud.ProofType]*pubsub.Topic),
+		stores:         make(map[fraud.ProofType]datastore.Datastore),
+		readStores:     make(map[fraud.ProofType]datastore.Datastore),
+		subs:           make(map[fraud.ProofType]*fanout[H]),
+		syncing:        make(map[[sha256.Size]byte]int),
+		broadcasting:   make(map[[sha256.Size]byte]trace.SpanContext),
+		topicUsed:      make(map[fraud.ProofType]time.Time),
+		idleTopics:     make(map[fraud.ProofType]struct{}),
+		registered:     make(map[fraud.ProofType]struct{}),
+		blacklisted:    make(map[peer.ID]BlacklistEntry),
+		pushes:         make(map[peer.ID]int),
+		ds:             ds,
+		networkID:      networkID,
+		syncerEnabled:  syncerEnabled,
+		keyHasher:      hex.EncodeToString,
+		storeNamespace: storePrefix,
+		clock:          realClock{},
+		tracer:         tracer,
+		pipeline:       DefaultPipeline[H](),
+	}
+	for _, opt := range opts {
+		opt(f)
 	}
+	if f.rawFallback {
+		f.unmarshal = fraud.RawFallback(f.unmarshal)
+	}
+	if f.dedupWindow > 0 {
+		f.seen = newSeenSet(f.dedupWindow, f.dedupMaxSize, f.clock)
+	}
+	if f.encryptionKey != nil {
+		ds, err := newEncryptedDatastore(f.ds, f.encryptionKey)
+		if err != nil {
+			panic(fmt.Sprintf("fraud: invalid store encryption key: %s", err))
+		}
+		f.ds = ds
+		if f.readDS != nil {
+			// the key has been validated above
+			f.readDS, _ = newEncryptedDatastore(f.readDS, f.encryptionKey)
+		}
+	}
+	return f
 }
 
 // registerProofTopics registers  as pubsub topics to be joined.
 func (f *ProofService[H]) registerProofTopics() error {
 	for _, proofType := range f.unmarshal.List() {
-		t, err := join(f.pubsub, proofType, f.networkID, f.processIncoming)
-		if err != nil {
+		if err := f.RegisterProofType(proofType); err != nil {
 			return err
 		}
-		f.topicsLk.Lock()
-		f.topics[proofType] = t
-		f.topicsLk.Unlock()
 	}
 	return nil
 }
 
+// RegisterProofType joins the pubsub topic of the given ProofType at runtime.
+// It errors unless the ProofUnmarshaler of the ProofService supports the ProofType.
+func (f *ProofService[H]) RegisterProofType(proofType fraud.ProofType) error {
+	if f.pubsub == nil {
+		return ErrPubSubDisabled
+	}
+	if !f.running() {
+		return ErrServiceNotRunning
+	}
+	if err := validateName("proof type", proofType.String()); err != nil {
+		return err
+	}
+	if !f.supports(proofType) {
+		return fmt.Errorf("proof type %s is not supported by the ProofUnmarshaler", proofType)
+	}
+
+	f.topicsLk.Lock()
+	defer f.topicsLk.Unlock()
+	if _, ok := f.topics[proofType]; ok {
+		return fmt.Errorf("topic for %s is already joined", proofType)
+	}
+	_, err := f.joinLocked(proofType)
+	return err
+}
+
+// joinLocked joins the topic of the given ProofType. It must be called with topicsLk held.
+func (f *ProofService[H]) joinLocked(proofType fraud.ProofType) (*pubsub.Topic, error) {
+	if _, ok := f.registered[proofType]; !ok && f.maxProofTypes > 0 && len(f.registered) >= f.maxProofTypes {
+		return nil, fmt.Errorf("%w: registering %s over the limit of %d", ErrTooManyProofTypes, proofType, f.maxProofTypes)
+	}
+	var opts []pubsub.TopicOpt
+	if f.msgIDFn != nil {
+		opts = append(opts, pubsub.WithTopicMessageIdFn(f.msgIDFn))
+	}
+	t, err := join(f.pubsub, proofType, f.networkID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	err = f.pubsub.RegisterTopicValidator(
+		t.String(),
+		func(ctx context.Context, from peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+			return f.processIncoming(ctx, proofType, from, msg)
+		},
+		pubsub.WithValidatorInline(f.inlineValidation),
+	)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("fraud: registering validator of %s: %w", proofType, err), t.Close())
+	}
+	if params, ok := f.topicScores[proofType]; ok {
+		if err = t.SetScoreParams(params); err != nil {
+			return nil, errors.Join(fmt.Errorf("fraud: setting score params of %s: %w", proofType, err), leave(f.pubsub, t))
+		}
+	}
+	f.topics[proofType] = t
+	f.registered[proofType] = struct{}{}
+	delete(f.idleTopics, proofType)
+	f.markUsedLocked(proofType)
+	return t, nil
+}
+
+// markUsedLocked records the use of the topic of the ProofType for WithAutoLeaveUnusedTopics.
+// It must be called with topicsLk held.
+func (f *ProofService[H]) markUsedLocked(proofType fraud.ProofType) {
+	if f.autoLeaveIdle > 0 {
+		f.topicUsed[proofType] = f.clock.Now()
+	}
+}
+
+// supports reports whether the ProofUnmarshaler lists the given ProofType.
+func (f *ProofService[H]) supports(proofType fraud.ProofType) bool {
+	for _, listed := range f.unmarshal.List() {
+		if listed == proofType {
+			return true
+		}
+	}
+	return false
+}
+
+// UnregisterProofType stops participating in the pubsub topic of the given ProofType at runtime.
+// It cancels all the subscriptions of the ProofType, leaves its topic and removes its verifier.
+// Stored proofs of the ProofType are kept and can be removed with Clear.
+func (f *ProofService[H]) UnregisterProofType(proofType fraud.ProofType) error {
+	f.subsLk.Lock()
+	fo := f.subs[proofType]
+	f.subsLk.Unlock()
+	if fo != nil {
+		// cancelling the last subscription cancels the underlying pubsub subscription
+		for _, sub := range fo.subscriptions() {
+			sub.Cancel()
+		}
+	}
+
+	f.verifiersLk.Lock()
+	delete(f.verifiers, proofType)
+	f.verifiersLk.Unlock()
+
+	f.topicsLk.Lock()
+	defer f.topicsLk.Unlock()
+	t, ok := f.topics[proofType]
+	if !ok {
+		return fmt.Errorf("%w: topic for %s does not exist", ErrTopicNotFound, proofType)
+	}
+	delete(f.topics, proofType)
+	delete(f.idleTopics, proofType)
+	return leave(f.pubsub, t)
+}
+
+// joinedTopics returns a snapshot of the joined topics, so that the lock is not held
+// while publishing, as publishing synchronously runs the validation pipeline.
+func (f *ProofService[H]) joinedTopics() map[fraud.ProofType]*pubsub.Topic {
+	f.topicsLk.RLock()
+	defer f.topicsLk.RUnlock()
+	topics := make(map[fraud.ProofType]*pubsub.Topic, len(f.topics))
+	for proofType, topic := range f.topics {
+		topics[proofType] = topic
+	}
+	return topics
+}
+
+// Topics returns the ProofTypes of the currently joined topics.
+func (f *ProofService[H]) Topics() []fraud.ProofType {
+	f.topicsLk.RLock()
+	defer f.topicsLk.RUnlock()
+	types := make([]fraud.ProofType, 0, len(f.topics))
+	for proofType := range f.topics {
+		types = append(types, proofType)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i] < types[j]
+	})
+	return types
+}
+
 // Start joins fraud proofs topics, sets the stream handler for fraudProtocolID and starts syncing
-// if syncer is enabled.
-func (f *ProofService[H]) Start(context.Context) error {
+// if syncer is enabled. Stored proofs are served to peers regardless of the syncer,
+// unless disabled with WithServeDisabled. It errors if the networkID or any of the proof types are not valid names.
+func (f *ProofService[H]) Start(ctx context.Context) error {
+	if err := validateName("network ID", f.networkID); err != nil {
+		return err
+	}
+	// proof types are used in datastore keys, so they are validated in storage-only mode as well
+	for _, proofType := range f.unmarshal.List() {
+		if err := validateName("proof type", proofType.String()); err != nil {
+			return err
+		}
+	}
 	f.ctx, f.cancel = context.WithCancel(context.Background())
-	if err := f.registerProofTopics(); err != nil {
+	if f.pubsub != nil {
+		if err := f.registerProofTopics(); err != nil {
+			return err
+		}
+	}
+	unknown, err := newUnknownTypes(f.clock)
+	if err != nil {
+		return err
+	}
+	f.unknownTypes = unknown
+	if f.subBufferSize > 0 {
+		metrics, err := newSubscriptionMetrics(f.bufferedMessages)
+		if err != nil {
+			return err
+		}
+		f.subMetrics = metrics
+	}
+	if f.seen != nil {
+		reg, err := newSeenSetMetrics(f.seen.size)
+		if err != nil {
+			return err
+		}
+		f.seenMetrics = reg
+	}
+	if f.maxTotalProofs > 0 {
+		metrics, err := newStoreFullMetrics()
+		if err != nil {
+			return err
+		}
+		f.storeFullMetrics = metrics
+
+		f.totalLk.Lock()
+		err = f.countProofs(ctx)
+		f.totalLk.Unlock()
+		if err != nil {
+			return fmt.Errorf("fraud: counting stored proofs: %w", err)
+		}
+	}
+	if len(f.typeQuotas) > 0 {
+		reg, err := newProofAgeMetrics(f.clock, f.oldestFirstSeen)
+		if err != nil {
+			return err
+		}
+		f.ageMetrics = reg
+	}
+	reg, err := newBlacklistMetrics(f.blacklistedAmount)
+	if err != nil {
 		return err
 	}
+	f.blacklistMetrics = reg
 	id := protocolID(f.networkID)
 	log.Infow("starting fraud proof service", "protocol ID", id)
 
-	f.host.SetStreamHandler(id, f.handleFraudMessageRequest)
+	// the fraud protocol carries the proofs pushed with BroadcastTo as well
+	if f.host != nil && (!f.serveDisabled || f.pubsub != nil) {
+		f.host.SetStreamHandler(id, f.handleFraudMessageRequest)
+	}
+	if f.host != nil && !f.serveDisabled && f.connectReconciliation {
+		f.host.SetStreamHandler(inventoryProtocolID(f.networkID), f.handleInventoryRequest)
+	}
+	if f.pubsub == nil {
+		return nil
+	}
+	if f.minPeerConnAge > 0 {
+		f.connAges, f.stopConnAges = newConnAges(f.host.Network(), f.clock)
+	}
 	if f.syncerEnabled {
+		metrics, err := newSyncMetrics(f.clock)
+		if err != nil {
+			return err
+		}
+		f.syncMetrics = metrics
 		go f.syncFraudProofs(f.ctx, id)
 	}
+	if f.rebroadcastInterval > 0 {
+		go f.rebroadcast(f.ctx)
+	}
+	if f.autoLeaveIdle > 0 {
+		go f.leaveUnusedTopics(f.ctx)
+	}
+	if f.connectReconciliation {
+		go f.reconcileOnConnect(f.ctx)
+	}
 	return nil
 }
 
-// Stop removes the stream handler and cancels the underlying ProofService
-func (f *ProofService[H]) Stop(context.Context) (err error) {
-	f.host.RemoveStreamHandler(protocolID(f.networkID))
-	f.topicsLk.Lock()
-	for tp, topic := range f.topics {
-		delete(f.topics, tp)
-		err = errors.Join(topic.Close())
+// Stop removes the stream handler, closes the joined topics and cancels the underlying ProofService.
+// Subscriptions waiting for proofs fail with ErrServiceStopped.
+// If the context is done before all the topics are closed, Stop returns with the context error,
+// while the remaining topics keep closing in the background.
+func (f *ProofService[H]) Stop(ctx context.Context) (err error) {
+	if f.host != nil {
+		f.host.RemoveStreamHandler(protocolID(f.networkID))
+		f.host.RemoveStreamHandler(inventoryProtocolID(f.networkID))
+	}
+	// cancel the pubsub subscriptions, so that the topics can be closed,
+	// which stops the subscriptions with ErrServiceStopped
+	f.subsLk.Lock()
+	subs := f.subs
+	f.subs = make(map[fraud.ProofType]*fanout[H])
+	f.subsLk.Unlock()
+	for _, fo := range subs {
+		fo.sub.Cancel()
 	}
+	f.topicsLk.Lock()
+	topics := f.topics
+	f.topics = make(map[fraud.ProofType]*pubsub.Topic)
 	f.topicsLk.Unlock()
-	f.cancel()
+	err = closeTopics(ctx, topics)
+	if f.cancel != nil {
+		f.cancel()
+	}
+	if f.stopConnAges != nil {
+		f.stopConnAges()
+	}
+	if f.subMetrics != nil {
+		err = errors.Join(err, f.subMetrics.close())
+		f.subMetrics = nil
+	}
+	if f.seenMetrics != nil {
+		err = errors.Join(err, f.seenMetrics.Unregister())
+		f.seenMetrics = nil
+	}
+	if f.ageMetrics != nil {
+		err = errors.Join(err, f.ageMetrics.Unregister())
+		f.ageMetrics = nil
+	}
+	if f.blacklistMetrics != nil {
+		err = errors.Join(err, f.blacklistMetrics.Unregister())
+		f.blacklistMetrics = nil
+	}
+	// the syncer may still be finishing, so syncMetrics is only unregistered, not reset
+	err = errors.Join(err, f.syncMetrics.close())
 	return
 }
 
+// closeTopics closes all the given topics concurrently, so that a blocking topic
+// does not prevent closing the others, and waits for them until the context is done.
+func closeTopics(ctx context.Context, topics map[fraud.ProofType]*pubsub.Topic) error {
+	errCh := make(chan error, len(topics))
+	for proofType, topic := range topics {
+		go func(proofType fraud.ProofType, topic *pubsub.Topic) {
+			if err := topic.Close(); err != nil {
+				errCh <- fmt.Errorf("closing topic for %s: %w", proofType, err)
+				return
+			}
+			errCh <- nil
+		}(proofType, topic)
+	}
+
+	var err error
+	for range topics {
+		select {
+		case closeErr := <-errCh:
+			err = errors.Join(err, closeErr)
+		case <-ctx.Done():
+			return errors.Join(err, fmt.Errorf("fraud: closing topics: %w", ctx.Err()))
+		}
+	}
+	return err
+}
+
+// Subscribe subscribes to the Proofs of the given ProofType.
+// The topic of the ProofType is joined if it is not yet, as long as the ProofUnmarshaler lists it.
 func (f *ProofService[H]) Subscribe(proofType fraud.ProofType) (_ fraud.Subscription[H], err error) {
+	if f.pubsub == nil {
+		return nil, ErrPubSubDisabled
+	}
+	if !f.running() {
+		return nil, ErrServiceNotRunning
+	}
+	sub, err := f.subscribe(proofType)
+	if err != nil {
+		return nil, err
+	}
+	if f.subReplay {
+		// the subscription is already receiving live proofs, so that none are missed in between
+		proofs, err := f.Get(f.ctx, proofType)
+		if err != nil && !errors.Is(err, datastore.ErrNotFound) {
+			sub.Cancel()
+			return nil, fmt.Errorf("fraud: replaying stored proofs: %w", err)
+		}
+		sub.replay(proofs)
+	}
+	return sub, nil
+}
+
+func (f *ProofService[H]) subscribe(proofType fraud.ProofType) (*subscription[H], error) {
 	f.topicsLk.Lock()
 	defer f.topicsLk.Unlock()
+	f.markUsedLocked(proofType)
 	t, ok := f.topics[proofType]
 	if !ok {
-		return nil, fmt.Errorf("topic for %s does not exist", proofType)
+		// join lazily, if the ProofType is supported, e.g. after it was unregistered or left unused
+		if !f.supports(proofType) {
+			return nil, fmt.Errorf("%w: topic for %s does not exist", ErrTopicNotFound, proofType)
+		}
+		var err error
+		if t, err = f.joinLocked(proofType); err != nil {
+			return nil, err
+		}
 	}
-	subs, err := t.Subscribe()
-	if err != nil {
-		return nil, err
+
+	f.subsLk.Lock()
+	defer f.subsLk.Unlock()
+	// all subscriptions of the topic share a single pubsub subscription
+	fo, ok := f.subs[proofType]
+	if !ok {
+		var opts []pubsub.SubOpt
+		if f.psSubBufferSize > 0 {
+			opts = append(opts, pubsub.WithBufferSize(f.psSubBufferSize))
+		}
+		psSub, err := t.Subscribe(opts...)
+		if err != nil {
+			return nil, err
+		}
+		metrics := f.subMetrics
+		fo = newFanout[H](psSub, func() {
+			log.Debugw("subscriber is too slow, dropping proof", "proofType", proofType)
+			metrics.observeDrop(f.ctx, proofType)
+		})
+		f.subs[proofType] = fo
+		go fo.run(f.ctx)
 	}
-	return &subscription[H]{subs}, nil
+
+	bufferSize := defaultSubscriptionBuffer
+	if f.subBufferSize > 0 {
+		bufferSize = f.subBufferSize
+	}
+	var sub *subscription[H]
+	sub = newSubscription[H](bufferSize, f.subReplay, func() {
+		f.unsubscribe(proofType, fo, sub)
+	})
+	fo.add(sub)
+	return sub, nil
+}
+
+// unsubscribe removes the subscription from the fanout
+// and cancels the pubsub subscription once no subscriptions are left.
+func (f *ProofService[H]) unsubscribe(proofType fraud.ProofType, fo *fanout[H], sub *subscription[H]) {
+	f.subsLk.Lock()
+	defer f.subsLk.Unlock()
+	if !fo.remove(sub) {
+		return
+	}
+	fo.sub.Cancel()
+	if f.subs[proofType] == fo {
+		delete(f.subs, proofType)
+	}
+}
+
+// bufferedMessages reports the amount of messages buffered in subscriptions per ProofType.
+func (f *ProofService[H]) bufferedMessages() map[fraud.ProofType]int {
+	f.subsLk.Lock()
+	defer f.subsLk.Unlock()
+	buffered := make(map[fraud.ProofType]int, len(f.subs))
+	for proofType, fo := range f.subs {
+		buffered[proofType] = fo.buffered()
+	}
+	return buffered
 }
 
 func (f *ProofService[H]) Broadcast(ctx context.Context, p fraud.Proof[H]) error {
+	_, err := f.broadcast(ctx, p)
+	return err
+}
+
+// BroadcastN broadcasts the Proof like Broadcast and returns the amount of peers of its topic
+// at the time of publishing. It is a best-effort estimate of how widely the Proof propagated,
+// helping to detect under-connected nodes.
+func (f *ProofService[H]) BroadcastN(ctx context.Context, p fraud.Proof[H]) (int, error) {
+	return f.broadcast(ctx, p)
+}
+
+// BroadcastAndWait waits until the topic of the Proof has at least minPeers peers
+// before broadcasting the Proof. It errors if the context is done before that.
+func (f *ProofService[H]) BroadcastAndWait(ctx context.Context, p fraud.Proof[H], minPeers int) error {
+	_, err := f.broadcast(ctx, p, pubsub.WithReadiness(pubsub.MinTopicSize(minPeers)))
+	return err
+}
+
+// broadcast publishes the Proof on its topic and returns the amount of the topic peers.
+func (f *ProofService[H]) broadcast(
+	ctx context.Context,
+	p fraud.Proof[H],
+	opts ...pubsub.PubOpt,
+) (peers int, err error) {
+	ctx, span := f.tracer.Start(ctx, "broadcast_proof", trace.WithAttributes(
+		attribute.String("proof_type", string(p.Type())),
+		attribute.Int("block_height", int(p.Height())),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetStatus(codes.Ok, "")
+		}
+		span.End()
+	}()
+
+	if f.pubsub == nil {
+		return 0, ErrPubSubDisabled
+	}
+	if !f.running() {
+		return 0, ErrServiceNotRunning
+	}
+	if err = f.checkExpiry(ctx, p); err != nil {
+		return 0, err
+	}
 	bin, err := p.MarshalBinary()
 	if err != nil {
-		return err
+		return 0, err
 	}
-	f.topicsLk.RLock()
-	t, ok := f.topics[p.Type()]
-	f.topicsLk.RUnlock()
-	if !ok {
-		return fmt.Errorf("fraud: unmarshaler for %s proof is not registered", p.Type())
+	t, err := f.publishTopic(p.Type())
+	if err != nil {
+		return 0, err
+	}
+	done := f.markBroadcasting(bin, span.SpanContext())
+	defer done()
+	if err = t.Publish(ctx, bin, opts...); err != nil {
+		return 0, err
 	}
-	return t.Publish(ctx, bin)
+	return len(t.ListPeers()), nil
+}
+
+// markBroadcasting keeps the span context of the broadcast of the proof until done is called.
+// Own publications are validated synchronously, so the validation and storage of the proof
+// are traced as a part of the broadcast.
+func (f *ProofService[H]) markBroadcasting(data []byte, sc trace.SpanContext) (done func()) {
+	key := sha256.Sum256(data)
+	f.broadcastingLk.Lock()
+	f.broadcasting[key] = sc
+	f.broadcastingLk.Unlock()
+	return func() {
+		f.broadcastingLk.Lock()
+		defer f.broadcastingLk.Unlock()
+		if f.broadcasting[key].Equal(sc) {
+			delete(f.broadcasting, key)
+		}
+	}
+}
+
+// broadcastSpan returns the span context of the broadcast of the proof, if it is being broadcast.
+func (f *ProofService[H]) broadcastSpan(data []byte) (trace.SpanContext, bool) {
+	f.broadcastingLk.Lock()
+	defer f.broadcastingLk.Unlock()
+	sc, ok := f.broadcasting[sha256.Sum256(data)]
+	return sc, ok
+}
+
+// publishTopic returns the topic to publish the proofs of the ProofType to,
+// re-joining it if it was left for being unused.
+func (f *ProofService[H]) publishTopic(proofType fraud.ProofType) (*pubsub.Topic, error) {
+	if f.autoLeaveIdle == 0 {
+		f.topicsLk.RLock()
+		defer f.topicsLk.RUnlock()
+		t, ok := f.topics[proofType]
+		if !ok {
+			return nil, fmt.Errorf("%w: unmarshaler for %s proof is not registered", ErrTopicNotFound, proofType)
+		}
+		return t, nil
+	}
+
+	f.topicsLk.Lock()
+	defer f.topicsLk.Unlock()
+	f.markUsedLocked(proofType)
+	if t, ok := f.topics[proofType]; ok {
+		return t, nil
+	}
+	if _, ok := f.idleTopics[proofType]; !ok {
+		return nil, fmt.Errorf("%w: unmarshaler for %s proof is not registered", ErrTopicNotFound, proofType)
+	}
+	return f.joinLocked(proofType)
+}
+
+// Ingest runs the validation pipeline of incoming proofs over the given Proof and stores it
+// without broadcasting, e.g. for proofs received over an API or read from a file.
+// It returns the ProcessStatus of the Proof with the reason if it is not accepted.
+// An accepted Proof may still fail to be stored, in which case the storing error is returned.
+// Once the store holds the maximum amount of proofs set with WithMaxTotalProofs, new Proofs are ignored.
+// With WithReadOnlyStore, the Proof is only validated.
+// Once the context is done, Ingest returns promptly with its error without storing the Proof.
+func (f *ProofService[H]) Ingest(ctx context.Context, proof fraud.Proof[H]) (ProcessStatus, error) {
+	bin, err := proof.MarshalBinary()
+	if err != nil {
+		return ProcessRejected, err
+	}
+	if _, ok := proof.(*fraud.RawProof[H]); ok && f.rawFallback {
+		// raw proofs can't be validated, so they are stored as is for serving them to peers
+		return f.putIngested(ctx, proof, bin)
+	}
+	res, _, err := f.validateCancellable(ctx, proof)
+	if ctx.Err() != nil {
+		return ProcessIgnored, ctx.Err()
+	}
+	if err != nil {
+		return processStatus(res), fmt.Errorf("fraud: ingesting %s proof: %w", proof.Type(), err)
+	}
+	return f.putIngested(ctx, proof, bin)
+}
+
+// putIngested stores the Proof accepted by Ingest, which is ignored once the store is full.
+func (f *ProofService[H]) putIngested(ctx context.Context, proof fraud.Proof[H], data []byte) (ProcessStatus, error) {
+	err := f.put(ctx, proof.Type(), f.keyHasher(proof.HeaderHash()), data)
+	if errors.Is(err, ErrStoreFull) {
+		return ProcessIgnored, err
+	}
+	return ProcessAccepted, err
+}
+
+// DryRun reports the ProcessStatus the given Proof would get from the validation pipeline
+// with the reason if it is not accepted, without storing or broadcasting it,
+// e.g. for proof producers to check their proofs. Like Ingest, it returns promptly once the context is done.
+func (f *ProofService[H]) DryRun(ctx context.Context, proof fraud.Proof[H]) (status ProcessStatus, reason error) {
+	res, _, err := f.validateCancellable(ctx, proof)
+	return processStatus(res), err
+}
+
+// validateCancellable runs the validation pipeline over the Proof like validate, but returns
+// promptly with the error of the context once it is done, leaving a blocked verifier behind.
+func (f *ProofService[H]) validateCancellable(
+	ctx context.Context,
+	proof fraud.Proof[H],
+) (pubsub.ValidationResult, ProcessReason, error) {
+	type validation struct {
+		res    pubsub.ValidationResult
+		reason ProcessReason
+		err    error
+	}
+	done := make(chan validation, 1)
+	go func() {
+		var v validation
+		defer func() {
+			// a panic can't be recovered by the caller from another goroutine, so the proof is rejected
+			if r := recover(); r != nil {
+				v = validation{res: pubsub.ValidationReject, err: fmt.Errorf("PANIC while validating a proof: %s", r)}
+			}
+			done <- v
+		}()
+		v.res, v.reason, v.err = f.validate(ctx, proof)
+	}()
+
+	select {
+	case v := <-done:
+		if ctx.Err() != nil {
+			return pubsub.ValidationIgnore, "", ctx.Err()
+		}
+		return v.res, v.reason, v.err
+	case <-ctx.Done():
+		return pubsub.ValidationIgnore, "", ctx.Err()
+	}
+}
+
+// running reports whether the ProofService is started and not yet stopped.
+func (f *ProofService[H]) running() bool {
+	return f.ctx != nil && f.ctx.Err() == nil
 }
 
 func (f *ProofService[H]) AddVerifier(proofType fraud.ProofType, verifier fraud.Verifier[H]) error {
+	if verifier == nil {
+		return fmt.Errorf("nil verifier for proof type %s", proofType)
+	}
 	f.verifiersLk.Lock()
 	defer f.verifiersLk.Unlock()
 	if _, ok := f.verifiers[proofType]; ok {
@@ -171,14 +879,56 @@ func (f *ProofService[H]) AddVerifier(proofType fraud.ProofType, verifier fraud.
 	return nil
 }
 
+// AddGetterVerifier is like AddVerifier, but the given GetterVerifier can access the stored
+// proofs of the ProofService during the verification.
+func (f *ProofService[H]) AddGetterVerifier(proofType fraud.ProofType, verifier fraud.GetterVerifier[H]) error {
+	if verifier == nil {
+		return fmt.Errorf("nil verifier for proof type %s", proofType)
+	}
+	return f.AddVerifier(proofType, func(proof fraud.Proof[H]) (bool, error) {
+		ctx := f.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		return verifier(ctx, f, proof)
+	})
+}
+
+// verifierFor returns the verifier for the given proof type if exists.
+// The lock is never held while the verifier runs, so a panicking verifier can't leak it.
+func (f *ProofService[H]) verifierFor(proofType fraud.ProofType) (fraud.Verifier[H], bool) {
+	f.verifiersLk.RLock()
+	defer f.verifiersLk.RUnlock()
+	verifier, ok := f.verifiers[proofType]
+	return verifier, ok
+}
+
 // processIncoming encompasses the logic for validating fraud proofs.
 func (f *ProofService[H]) processIncoming(
 	ctx context.Context,
 	proofType fraud.ProofType,
 	from peer.ID,
 	msg *pubsub.Message,
+) pubsub.ValidationResult {
+	return f.processDecoded(ctx, proofType, from, msg, nil)
+}
+
+// processDecoded is like processIncoming, but for the message with the Proof already unmarshalled
+// from it, if not nil.
+func (f *ProofService[H]) processDecoded(
+	ctx context.Context,
+	proofType fraud.ProofType,
+	from peer.ID,
+	msg *pubsub.Message,
+	proof fraud.Proof[H],
 ) (res pubsub.ValidationResult) {
-	ctx, span := tracer.Start(ctx, "process_proof", trace.WithAttributes(
+	// own broadcasts are validated synchronously, so they are traced as a part of the broadcast
+	if from == f.host.ID() {
+		if sc, ok := f.broadcastSpan(msg.Data); ok {
+			ctx = trace.ContextWithSpanContext(ctx, sc)
+		}
+	}
+	ctx, span := f.tracer.Start(ctx, "process_proof", trace.WithAttributes(
 		attribute.String("proof_type", string(proofType)),
 	))
 	defer span.End()
@@ -187,148 +937,660 @@ func (f *ProofService[H]) processIncoming(
 		r := recover()
 		if r != nil {
 			err := fmt.Errorf("PANIC while processing a proof: %s", r)
-			log.Error(err)
+			log.Errorw("fraud proof processing panicked", "err", err,
+				"networkID", f.networkID, "proofType", proofType)
 			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
 			res = pubsub.ValidationReject
 		}
 	}()
 
-	// unmarshal message to the Proof.
-	// Peer will be added to black list if unmarshalling fails.
-	proof, err := f.unmarshal.Unmarshal(proofType, msg.Data)
+	// ignore our own proofs looping back from the network, e.g. through a misconfigured relay,
+	// while our own publications are validated with ourselves as the sender.
+	if from != f.host.ID() && peer.ID(msg.GetFrom()) == f.host.ID() {
+		log.Debugw("ignoring own proof received back", "networkID", f.networkID,
+			"proofType", proofType, "peer", from)
+		span.AddEvent("received_own_proof")
+		return pubsub.ValidationIgnore
+	}
+	// ignore proofs from freshly connected peers, likely sybils, so they are retried once the connection matures.
+	if f.minPeerConnAge > 0 && from != f.host.ID() && f.connAges.age(from) < f.minPeerConnAge {
+		log.Debugw("ignoring proof from freshly connected peer", "networkID", f.networkID,
+			"proofType", proofType, "peer", from)
+		span.AddEvent("received_from_fresh_peer")
+		return pubsub.ValidationIgnore
+	}
+
+	return f.runPipeline(ctx, &Incoming[H]{Type: proofType, From: from, Message: msg, Proof: proof, serv: f})
+}
+
+// putIncoming stores the incoming Proof accepted by the validation. Failing to store it doesn't
+// affect the acceptance, unless the store is full, in which case the Proof is ignored.
+func (f *ProofService[H]) putIncoming(
+	ctx context.Context,
+	span trace.Span,
+	proof fraud.Proof[H],
+	data []byte,
+) (pubsub.ValidationResult, bool) {
+	err := f.put(ctx, proof.Type(), f.keyHasher(proof.HeaderHash()), data)
+	if errors.Is(err, ErrStoreFull) {
+		f.logDecision(proof, pubsub.ValidationIgnore, ReasonStoreFull, nil)
+		span.AddEvent("store_full")
+		return pubsub.ValidationIgnore, false
+	}
 	if err != nil {
-		log.Errorw("unmarshalling failed", "err", err)
-		if !errors.Is(err, &fraud.ErrNoUnmarshaler{}) {
-			f.pubsub.BlacklistPeer(from)
-		}
 		span.RecordError(err)
-		return pubsub.ValidationReject
-	}
-	// check the fraud proof locally and ignore if it has been already stored locally.
-	if f.verifyLocal(ctx, proofType, hex.EncodeToString(proof.HeaderHash()), msg.Data) {
-		span.AddEvent("received_known_fraud_proof", trace.WithAttributes(
-			attribute.String("proof_type", string(proof.Type())),
-			attribute.Int("block_height", int(proof.Height())),
-			attribute.String("block_hash", hex.EncodeToString(proof.HeaderHash())),
-			attribute.String("from_peer", from.String()),
-		))
-		return pubsub.ValidationIgnore
 	}
+	return pubsub.ValidationAccept, true
+}
+
+// setValidatorData attaches the data to the message for the local subscriptions to deliver.
+// ValidatorData of an unexpected type, set by another validator on the topic, is not clobbered.
+// Instead, a warning is logged, so that the misconfiguration of the topic is detectable.
+func (f *ProofService[H]) setValidatorData(msg *pubsub.Message, data interface{}) bool {
+	switch msg.ValidatorData.(type) {
+	case nil, fraud.Proof[H], republished:
+		msg.ValidatorData = data
+		return true
+	}
+	log.Warnw("message data is already set by another validator, check the topic configuration",
+		"networkID", f.networkID, "topic", msg.GetTopic(), "type", reflect.TypeOf(msg.ValidatorData))
+	return false
+}
+
+// resultFor returns the pubsub ValidationResult for the Proof not accepted for the given reason,
+// as mapped by the policy set with WithResultPolicy, if any, or the given default otherwise.
+// The policy can't accept the Proof, so acceptance is turned into ValidationIgnore.
+func (f *ProofService[H]) resultFor(reason ProcessReason, def pubsub.ValidationResult) pubsub.ValidationResult {
+	if f.resultPolicy == nil {
+		return def
+	}
+	if res := f.resultPolicy(reason); res != pubsub.ValidationAccept {
+		return res
+	}
+	return pubsub.ValidationIgnore
+}
 
+// logDecision logs the decision on the incoming Proof with the fields identifying the Proof
+// across networks. Decisions caused by an error are logged as errors, others for debugging.
+func (f *ProofService[H]) logDecision(
+	proof fraud.Proof[H],
+	res pubsub.ValidationResult,
+	reason ProcessReason,
+	err error,
+) {
+	hash := proof.HeaderHash()
+	if len(hash) > shortHashLen {
+		hash = hash[:shortHashLen]
+	}
+	fields := []interface{}{
+		"networkID", f.networkID,
+		"proofType", proof.Type(),
+		"height", proof.Height(),
+		"hash", hex.EncodeToString(hash),
+		"result", resultString(res),
+	}
+	if reason != "" {
+		fields = append(fields, "reason", reason)
+	}
+	if err != nil {
+		log.Errorw("fraud proof validation failed", append(fields, "err", err)...)
+		return
+	}
+	log.Debugw("fraud proof processed", fields...)
+}
+
+// validate runs the stages of the pipeline over the Proof, skipping the ones specific to
+// the messages received from the network, like the unmarshalling and the storage.
+// It returns the pubsub ValidationResult of the Proof and,
+// if it is not accepted, the reason with the error.
+func (f *ProofService[H]) validate(
+	ctx context.Context,
+	proof fraud.Proof[H],
+) (pubsub.ValidationResult, ProcessReason, error) {
+	res, reason, err := f.runStages(ctx, &Incoming[H]{Type: proof.Type(), Proof: proof, serv: f})
+	if res == pubsub.ValidationAccept {
+		return res, "", nil
+	}
+	if err == nil {
+		err = fmt.Errorf("proof not accepted: %s", reason)
+	}
+	return res, reason, err
+}
+
+// maxHeight returns the max allowed height of the Proof given by the head threshold of its type,
+// rejecting the Proof if it is fraud.Expiring and expired at the network head.
+// The head threshold is skipped for the proofs fetched by the sync.
+func (f *ProofService[H]) maxHeight(
+	ctx context.Context,
+	proof fraud.Proof[H],
+	synced bool,
+) (uint64, pubsub.ValidationResult, ProcessReason, error) {
+	maxHeight := uint64(math.MaxUint64)
+	skipThreshold := f.skipHeadThreshold || synced
+	expiring, isExpiring := proof.(fraud.Expiring)
+	if skipThreshold && !isExpiring {
+		return maxHeight, pubsub.ValidationAccept, "", nil
+	}
 	head, err := f.headGetter(ctx)
 	if err != nil {
-		log.Errorw("failed to fetch current network head to verify a fraud proof",
-			"err", err, "proofType", proof.Type(), "height", proof.Height())
-		return pubsub.ValidationIgnore
+		return 0, pubsub.ValidationIgnore, ReasonHeadUnavailable, fmt.Errorf("fetching network head: %w", err)
+	}
+	if isExpiring && expiring.Expiry() < head.Height() {
+		return 0, pubsub.ValidationReject, ReasonExpired,
+			fmt.Errorf("proof expired at height %d, network head is at %d", expiring.Expiry(), head.Height())
+	}
+	if !skipThreshold {
+		threshold, ok := f.headThresholds[proof.Type()]
+		if !ok {
+			threshold = headThreshold
+		}
+		maxHeight = head.Height() + threshold
 	}
+	return maxHeight, pubsub.ValidationAccept, "", nil
+}
 
-	if head.Height()+headThreshold < proof.Height() {
-		err = fmt.Errorf("received proof above the max threshold."+
-			"maxHeight: %d, proofHeight: %d, proofType: %s",
-			head.Height()+headThreshold,
-			proof.Height(),
-			proof.Type(),
-		)
-		log.Error(err)
-		span.RecordError(err)
-		return pubsub.ValidationReject
+// activeVerifier returns the verifier of the given ProofType, batching the verifications
+// if WithBatchVerifier is set for the type, or nil if there is none.
+func (f *ProofService[H]) activeVerifier(ctx context.Context, proofType fraud.ProofType) fraud.Verifier[H] {
+	verifier, _ := f.verifierFor(proofType)
+	if batch, ok := f.batchVerifiers[proofType]; ok {
+		batchCtx := f.ctx
+		if batchCtx == nil {
+			batchCtx = context.Background()
+		}
+		verifier = batch.verifier(ctx, batchCtx)
 	}
+	return verifier
+}
 
-	msg.ValidatorData = proof
+func (f *ProofService[H]) Get(ctx context.Context, proofType fraud.ProofType) ([]fraud.Proof[H], error) {
+	return getAll(ctx, f.readStoreFor(proofType), proofType, f.unmarshal, f.queryBatchSize)
+}
 
-	// fetch extended header in order to verify the fraud proof.
-	extHeader, err := f.headerGetter(ctx, proof.Height())
+// GetByHeightRange fetches the stored proofs of the ProofType with heights within the inclusive
+// range [from, to], ordered by their heights. Like Get, it returns datastore.ErrNotFound
+// if there are no such proofs.
+func (f *ProofService[H]) GetByHeightRange(
+	ctx context.Context,
+	proofType fraud.ProofType,
+	from, to uint64,
+) ([]fraud.Proof[H], error) {
+	if from > to {
+		return nil, fmt.Errorf("fraud: invalid height range [%d, %d]", from, to)
+	}
+	// the store keys don't encode the heights, so all the proofs are filtered
+	proofs, err := f.Get(ctx, proofType)
 	if err != nil {
-		log.Errorw("failed to fetch header to verify a fraud proof",
-			"err", err, "proofType", proof.Type(), "height", proof.Height())
-		return pubsub.ValidationIgnore
+		return nil, err
+	}
+	inRange := make([]fraud.Proof[H], 0, len(proofs))
+	for _, proof := range proofs {
+		if proof.Height() >= from && proof.Height() <= to {
+			inRange = append(inRange, proof)
+		}
+	}
+	if len(inRange) == 0 {
+		return nil, datastore.ErrNotFound
 	}
+	return inRange, nil
+}
 
-	// execute the verifier for proof type if exists
-	f.verifiersLk.RLock()
-	verifier, ok := f.verifiers[proofType]
-	f.verifiersLk.RUnlock()
-	if ok {
-		status, err := verifier(proof)
+// GetRaw fetches the stored proofs of the ProofType in their encoded form, without unmarshalling them.
+// It is a cheaper alternative to Get for components only passing the proofs through, e.g. relays.
+// Unlike Get, the proofs are ordered by their storage keys, not by their heights.
+func (f *ProofService[H]) GetRaw(ctx context.Context, proofType fraud.ProofType) ([][]byte, error) {
+	return getAllRaw(ctx, f.readStoreFor(proofType))
+}
+
+// WaitForProof returns the first proof of the given ProofType matching the given func,
+// checking the stored proofs first and waiting for incoming ones otherwise.
+// Without pubsub, only the stored proofs are checked.
+func (f *ProofService[H]) WaitForProof(
+	ctx context.Context,
+	proofType fraud.ProofType,
+	match func(fraud.Proof[H]) bool,
+) (fraud.Proof[H], error) {
+	// subscribe before checking the store, so that no proof is missed in between
+	sub, subErr := f.Subscribe(proofType)
+	if subErr == nil {
+		defer sub.Cancel()
+	}
+
+	proofs, err := f.Get(ctx, proofType)
+	if err != nil && !errors.Is(err, datastore.ErrNotFound) {
+		return nil, err
+	}
+	for _, proof := range proofs {
+		if match(proof) {
+			return proof, nil
+		}
+	}
+	if subErr != nil {
+		return nil, subErr
+	}
+
+	for {
+		proof, err := sub.Proof(ctx)
 		if err != nil {
-			log.Errorw("failed to run the verifier", "err", err, "proofType", proof.Type())
-			return pubsub.ValidationReject
+			return nil, err
 		}
-		if !status {
-			log.Errorw("invalid fraud proof", "proofType", proof.Type())
-			return pubsub.ValidationReject
+		if match(proof) {
+			return proof, nil
 		}
 	}
+}
 
-	// validate the fraud proof.
-	// Peer will be added to black list if the validation fails.
-	err = proof.Validate(extHeader)
-	if err != nil {
-		log.Errorw("proof validation err: ",
-			"err", err, "proofType", proof.Type(), "height", proof.Height())
-		f.pubsub.BlacklistPeer(from)
-		span.RecordError(err)
-		return pubsub.ValidationReject
+// GetAll fetches the stored proofs of all the supported ProofTypes.
+// ProofTypes without stored proofs are omitted. Failing ProofTypes do not hide the others:
+// the proofs of the successfully read ProofTypes are returned together with
+// a *GetAllError identifying the failed ones.
+func (f *ProofService[H]) GetAll(ctx context.Context) (map[fraud.ProofType][]fraud.Proof[H], error) {
+	all := make(map[fraud.ProofType][]fraud.Proof[H])
+	errs := make(map[fraud.ProofType]error)
+	for _, proofType := range f.unmarshal.List() {
+		proofs, err := f.Get(ctx, proofType)
+		switch {
+		case err == nil:
+			all[proofType] = proofs
+		case !errors.Is(err, datastore.ErrNotFound):
+			errs[proofType] = err
+		}
+	}
+	if len(errs) != 0 {
+		return all, &GetAllError{Errors: errs}
 	}
+	return all, nil
+}
 
-	span.AddEvent("received_valid_proof", trace.WithAttributes(
-		attribute.String("proof_type", string(proof.Type())),
-		attribute.Int("block_height", int(proof.Height())),
-		attribute.String("block_hash", hex.EncodeToString(proof.HeaderHash())),
-		attribute.String("from_peer", from.String()),
-	))
+// GetAllError is returned by GetAll with the errors of the ProofTypes that failed to be read.
+type GetAllError struct {
+	Errors map[fraud.ProofType]error
+}
 
-	// add the fraud proof to storage.
-	err = f.put(ctx, proof.Type(), hex.EncodeToString(proof.HeaderHash()), msg.Data)
-	if err != nil {
-		log.Errorw("failed to store fraud proof", "err", err)
-		span.RecordError(err)
+func (e *GetAllError) Error() string {
+	types := make([]string, 0, len(e.Errors))
+	for proofType, err := range e.Errors {
+		types = append(types, fmt.Sprintf("%s: %s", proofType, err))
 	}
+	sort.Strings(types)
+	return fmt.Sprintf("fraud: getting proofs of %d types failed: %s", len(types), strings.Join(types, "; "))
+}
 
-	span.SetStatus(codes.Ok, "")
-	return pubsub.ValidationAccept
+func (e *GetAllError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
 }
 
-func (f *ProofService[H]) Get(ctx context.Context, proofType fraud.ProofType) ([]fraud.Proof[H], error) {
-	f.storesLk.Lock()
-	store, ok := f.stores[proofType]
-	if !ok {
-		store = initStore(proofType, f.ds)
-		f.stores[proofType] = store
+// Clear removes all the stored proofs of the given ProofType.
+func (f *ProofService[H]) Clear(ctx context.Context, proofType fraud.ProofType) error {
+	defer f.lockStore()()
+	for _, store := range []datastore.Datastore{f.storeFor(proofType), f.firstSeenStore(proofType)} {
+		if err := deleteAll(ctx, store); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResetStore deletes the stored proofs of all the types, including the ones no longer supported,
+// together with the data kept alongside them, and clears the in-memory caches of the ProofService.
+// It is a heavier alternative to Clear for recovery and is safe to call while running.
+func (f *ProofService[H]) ResetStore(ctx context.Context) error {
+	defer f.lockStore()()
+	for _, root := range []string{f.storeNamespace, f.storeNamespace + firstSeenSuffix} {
+		if err := deleteAll(ctx, namespace.Wrap(f.ds, datastore.NewKey(root))); err != nil {
+			return fmt.Errorf("fraud: resetting store %s: %w", root, err)
+		}
 	}
+
+	f.storesLk.Lock()
+	f.stores = make(map[fraud.ProofType]datastore.Datastore)
+	f.readStores = make(map[fraud.ProofType]datastore.Datastore)
 	f.storesLk.Unlock()
+	if f.seen != nil {
+		f.seen.reset()
+	}
+	return nil
+}
 
-	return getAll(ctx, store, proofType, f.unmarshal)
+// ApplyVerifier runs the given Verifier over the stored proofs of the given ProofType,
+// e.g. after a stricter Verifier is added. Proofs failing the Verifier or erroring in it are
+// counted as dropped and are deleted only if dropFailing is set.
+func (f *ProofService[H]) ApplyVerifier(
+	ctx context.Context,
+	proofType fraud.ProofType,
+	v fraud.Verifier[H],
+	dropFailing bool,
+) (kept, dropped int, err error) {
+	if v == nil {
+		return 0, 0, errors.New("fraud: nil verifier")
+	}
+	defer f.lockStore()()
+	store := f.storeFor(proofType)
+	entries, err := query(ctx, store, q.Query{})
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, entry := range entries {
+		proof, err := f.unmarshal.Unmarshal(proofType, entry.Value)
+		if err != nil {
+			return kept, dropped, fmt.Errorf("unmarshalling proof %s: %w", entry.Key, err)
+		}
+		ok, err := v(proof)
+		if err != nil {
+			log.Warnw("failed to run the verifier over stored proof",
+				"err", err, "proofType", proofType, "height", proof.Height())
+		}
+		if ok && err == nil {
+			kept++
+			continue
+		}
+		dropped++
+		if !dropFailing {
+			continue
+		}
+		if err = store.Delete(ctx, datastore.NewKey(entry.Key)); err != nil {
+			return kept, dropped, err
+		}
+	}
+	return kept, dropped, nil
+}
+
+// storeFor returns the store for the given proof type, initializing it if needed.
+func (f *ProofService[H]) storeFor(proofType fraud.ProofType) datastore.Datastore {
+	return f.cachedStore(proofType, false)
 }
 
-// put adds a fraud proof to the local storage.
-func (f *ProofService[H]) put(ctx context.Context, proofType fraud.ProofType, hash string, data []byte) error {
+// readStoreFor returns the store for reading proofs of the given type,
+// which is the read datastore set with WithSplitStore, if any, or the store for the type otherwise.
+func (f *ProofService[H]) readStoreFor(proofType fraud.ProofType) datastore.Datastore {
+	return f.cachedStore(proofType, f.readDS != nil)
+}
+
+// cachedStore returns the store for the given proof type in the read datastore or the write one,
+// initializing it if needed.
+func (f *ProofService[H]) cachedStore(proofType fraud.ProofType, read bool) datastore.Datastore {
+	f.storesLk.RLock()
+	stores, ds := f.stores, f.ds
+	if read {
+		stores, ds = f.readStores, f.readDS
+	}
+	store, ok := stores[proofType]
+	f.storesLk.RUnlock()
+	if ok {
+		return store
+	}
+
 	f.storesLk.Lock()
-	store, ok := f.stores[proofType]
+	defer f.storesLk.Unlock()
+	stores = f.stores
+	if read {
+		stores = f.readStores
+	}
+	store, ok = stores[proofType]
 	if !ok {
-		store = initStore(proofType, f.ds)
-		f.stores[proofType] = store
+		store = initStore(f.storeNamespace, proofType, ds)
+		stores[proofType] = store
 	}
-	f.storesLk.Unlock()
-	return put(ctx, store, hash, data)
+	return store
+}
+
+// StorageKey returns the datastore key under which the ProofService stores the Proof of the
+// given type for the given header hash. It accounts for the configured store namespace and
+// key hasher.
+func (f *ProofService[H]) StorageKey(proofType fraud.ProofType, headerHash []byte) datastore.Key {
+	return makeKey(f.storeNamespace, proofType).ChildString(f.keyHasher(headerHash))
+}
+
+// Known checks whether a proof of the given type for the given header hash is already stored
+// locally. If WithRemoteKnownCheck is enabled and the proof is not found locally,
+// connected peers are asked for their proofs of the type as well, unless there is no host.
+func (f *ProofService[H]) Known(ctx context.Context, proofType fraud.ProofType, headerHash []byte) (bool, error) {
+	_, err := getByHash(ctx, f.readStoreFor(proofType), f.keyHasher(headerHash))
+	switch {
+	case err == nil:
+		return true, nil
+	case !errors.Is(err, datastore.ErrNotFound):
+		return false, err
+	case !f.remoteKnownCheck, f.host == nil:
+		return false, nil
+	}
+
+	id := protocolID(f.networkID)
+	for i, pid := range f.host.Network().Peers() {
+		if i >= fraudRequests {
+			break
+		}
+		resp, err := f.requestProofs(ctx, id, pid, []string{string(proofType)})
+		if err != nil {
+			log.Debugw("requesting proofs for known check", "err", err, "peer", pid)
+			continue
+		}
+		for _, data := range resp {
+			for _, val := range data.Value {
+				proof, err := f.unmarshal.Unmarshal(proofType, val)
+				if err != nil {
+					log.Debugw("unmarshalling proof for known check", "err", err, "peer", pid)
+					continue
+				}
+				if bytes.Equal(proof.HeaderHash(), headerHash) {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, ctx.Err()
+}
+
+// MigrateKeys rewrites the keys of all stored proofs of the registered types
+// to the scheme of the configured key hasher. It should be run after changing
+// the hasher via WithKeyHasher, as proofs stored under the old scheme are not found otherwise.
+func (f *ProofService[H]) MigrateKeys(ctx context.Context) error {
+	defer f.lockStore()()
+	for _, proofType := range f.unmarshal.List() {
+		store := f.storeFor(proofType)
+		entries, err := query(ctx, store, q.Query{})
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			proof, err := f.unmarshal.Unmarshal(proofType, entry.Value)
+			if err != nil {
+				return fmt.Errorf("unmarshalling proof %s: %w", entry.Key, err)
+			}
+			key := datastore.NewKey(f.keyHasher(proof.HeaderHash()))
+			if key.String() == entry.Key {
+				continue
+			}
+			if err = store.Put(ctx, key, entry.Value); err != nil {
+				return err
+			}
+			if err = store.Delete(ctx, datastore.NewKey(entry.Key)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MigrateStore rewrites all the stored proofs, including the ones of types no longer supported,
+// with the given migrate func for evolving the storage schema. The func gets the key of every proof
+// relative to the store namespace, e.g. "/<ProofType>/<hash>", with its value and returns the new key
+// and value to store it under, or keep as false to drop it. Entries with unchanged keys and values
+// are left untouched, while new keys must not collide with the other existing ones.
+// The first-seen timestamps of the proofs with a quota move along with them.
+// Storing proofs is blocked until the migration is done, so it should preferably be run before Start.
+func (f *ProofService[H]) MigrateStore(
+	ctx context.Context,
+	migrate func(oldKey string, value []byte) (newKey string, newValue []byte, keep bool),
+) error {
+	defer f.lockStore()()
+	store := namespace.Wrap(f.ds, datastore.NewKey(f.storeNamespace))
+	firstSeen := namespace.Wrap(f.ds, datastore.NewKey(f.storeNamespace+firstSeenSuffix))
+	entries, err := query(ctx, store, q.Query{})
+	if err != nil {
+		return fmt.Errorf("fraud: migrating store: %w", err)
+	}
+	for _, entry := range entries {
+		oldKey := datastore.NewKey(entry.Key)
+		newKey, newValue, keep := migrate(entry.Key, entry.Value)
+		if !keep {
+			if err = store.Delete(ctx, oldKey); err != nil {
+				return fmt.Errorf("fraud: migrating proof %s: %w", entry.Key, err)
+			}
+			if err = moveFirstSeen(ctx, firstSeen, oldKey, datastore.Key{}, false); err != nil {
+				return fmt.Errorf("fraud: migrating proof %s: %w", entry.Key, err)
+			}
+			continue
+		}
+		key := datastore.NewKey(newKey)
+		if key == oldKey && bytes.Equal(newValue, entry.Value) {
+			continue
+		}
+		if err = store.Put(ctx, key, newValue); err != nil {
+			return fmt.Errorf("fraud: migrating proof %s: %w", entry.Key, err)
+		}
+		if key != oldKey {
+			if err = store.Delete(ctx, oldKey); err != nil {
+				return fmt.Errorf("fraud: migrating proof %s: %w", entry.Key, err)
+			}
+			if err = moveFirstSeen(ctx, firstSeen, oldKey, key, true); err != nil {
+				return fmt.Errorf("fraud: migrating proof %s: %w", entry.Key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// put adds a fraud proof to the local storage, unless the store is read-only.
+func (f *ProofService[H]) put(ctx context.Context, proofType fraud.ProofType, hash string, data []byte) (err error) {
+	if f.readOnlyStore {
+		return nil
+	}
+	// datastores may not respect the context, so avoid writing once it is done
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	ctx, span := f.tracer.Start(ctx, "store_proof", trace.WithAttributes(
+		attribute.String("proof_type", string(proofType)),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	var known bool
+	if f.maxTotalProofs > 0 {
+		f.totalLk.Lock()
+		defer f.totalLk.Unlock()
+		if known, err = f.checkCapacity(ctx, proofType, hash); err != nil {
+			return err
+		}
+	}
+	var evicted int
+	if quota, ok := f.typeQuotas[proofType]; ok {
+		evicted, err = f.putWithQuota(ctx, proofType, quota, hash, data)
+	} else {
+		err = put(ctx, f.storeFor(proofType), hash, data)
+	}
+	if f.maxTotalProofs > 0 {
+		switch {
+		case err != nil:
+			// the proof may have been stored or others evicted regardless
+			f.totalCounted = false
+		case !known:
+			f.totalProofs += 1 - evicted
+		}
+	}
+	if err == nil && f.syncWrites {
+		err = f.Flush(ctx)
+	}
+	f.trackWrite(err)
+	return err
+}
+
+// Flush makes the stored proofs durable by syncing the namespaces of the ProofService
+// in the datastore, for datastores buffering writes.
+func (f *ProofService[H]) Flush(ctx context.Context) error {
+	for _, root := range []string{f.storeNamespace, f.storeNamespace + firstSeenSuffix} {
+		if err := f.ds.Sync(ctx, datastore.NewKey(root)); err != nil {
+			return fmt.Errorf("fraud: syncing store %s: %w", root, err)
+		}
+	}
+	return nil
+}
+
+// trackWrite tracks consecutive write failures to detect a persistently failing store,
+// logging only the first failure and the moment the failure becomes persistent.
+func (f *ProofService[H]) trackWrite(err error) {
+	f.writesLk.Lock()
+	defer f.writesLk.Unlock()
+	if err == nil {
+		f.failedWrites, f.lastWriteErr = 0, nil
+		return
+	}
+	f.failedWrites++
+	f.lastWriteErr = err
+	switch f.failedWrites {
+	case 1:
+		log.Errorw("failed to store fraud proof", "err", err)
+	case persistentWriteFailures:
+		log.Errorw("storing fraud proofs keeps failing, consider WithReadOnlyStore for read-only stores",
+			"err", err, "failures", f.failedWrites)
+	default:
+		log.Debugw("failed to store fraud proof", "err", err, "failures", f.failedWrites)
+	}
+}
+
+// Health reports whether the ProofService is able to persist proofs.
+// It returns an error wrapping ErrStoreUnwritable once writes to the store keep failing,
+// until a write succeeds again.
+func (f *ProofService[H]) Health() error {
+	f.writesLk.Lock()
+	defer f.writesLk.Unlock()
+	if f.failedWrites < persistentWriteFailures {
+		return nil
+	}
+	return fmt.Errorf("%w: %d consecutive failures: %w", ErrStoreUnwritable, f.failedWrites, f.lastWriteErr)
 }
 
 // verifyLocal checks if a fraud proof has been stored locally.
+// Proofs are compared by their canonical form, so that the same proof encoded differently,
+// e.g. by a peer serving it during sync, is not treated as a new one.
+// With WithSplitStoreFallback, proofs missing from the read datastore are looked up in the write one.
 func (f *ProofService[H]) verifyLocal(ctx context.Context, proofType fraud.ProofType, hash string, data []byte) bool {
-	f.storesLk.RLock()
-	storage, ok := f.stores[proofType]
-	f.storesLk.RUnlock()
-	if !ok {
-		return false
+	stored, err := getByHash(ctx, f.readStoreFor(proofType), hash)
+	if errors.Is(err, datastore.ErrNotFound) && f.readDS != nil && f.readFallback {
+		stored, err = getByHash(ctx, f.storeFor(proofType), hash)
 	}
-
-	proof, err := getByHash(ctx, storage, hash)
 	if err != nil {
 		if !errors.Is(err, datastore.ErrNotFound) {
 			log.Error(err)
 		}
 		return false
 	}
+	if bytes.Equal(stored, data) {
+		return true
+	}
 
-	return bytes.Equal(proof, data)
+	canonical := make([][]byte, 0, 2)
+	for _, bin := range [][]byte{stored, data} {
+		proof, err := f.unmarshal.Unmarshal(proofType, bin)
+		if err != nil {
+			return false
+		}
+		bin, err = fraud.CanonicalBytes(proof)
+		if err != nil {
+			return false
+		}
+		canonical = append(canonical, bin)
+	}
+	return bytes.Equal(canonical[0], canonical[1])
 }
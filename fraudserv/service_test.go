@@ -3,19 +3,33 @@ package fraudserv
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ipfs/go-datastore"
+	q "github.com/ipfs/go-datastore/query"
 	"github.com/ipfs/go-datastore/sync"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
 	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
 	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/celestiaorg/go-libp2p-messenger/serde"
 
 	"github.com/celestiaorg/go-header/headertest"
 
 	"github.com/celestiaorg/go-fraud"
+	pb "github.com/celestiaorg/go-fraud/fraudserv/pb"
 	"github.com/celestiaorg/go-fraud/fraudtest"
 )
 
@@ -41,6 +55,237 @@ func TestService_processIncomingRecovery(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestService_processIncomingOwnProof(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	// our own proof received back through a peer before it was stored
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	msg := &pubsub.Message{Message: &pubsub_pb.Message{
+		Data: mustMarshal(t, proof),
+		From: []byte(serv.host.ID()),
+	}}
+	res := serv.processIncoming(ctx, proof.Type(), "peer", msg)
+	require.Equal(t, pubsub.ValidationIgnore, res)
+	_, err := serv.Get(ctx, proof.Type())
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+
+	// while our own publications are still processed
+	require.NoError(t, serv.Broadcast(ctx, proof))
+	_, err = serv.Get(ctx, proof.Type())
+	require.NoError(t, err)
+}
+
+func TestService_processIncomingForeignValidatorData(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	// emulate another validator on the topic setting ValidatorData of its own
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	msg := &pubsub.Message{
+		Message:       &pubsub_pb.Message{Data: mustMarshal(t, proof)},
+		ValidatorData: "bogus",
+	}
+	res := serv.processIncoming(ctx, proof.Type(), "peer", msg)
+	require.Equal(t, pubsub.ValidationIgnore, res)
+	require.Equal(t, "bogus", msg.ValidatorData)
+	_, err := serv.Get(ctx, proof.Type())
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+}
+
+func TestService_MinPeerConnAge(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(3)
+	require.NoError(t, err)
+	// the peer connected before the start is aged by the clock from the start on
+	_, err = net.ConnectPeers(net.Hosts()[0].ID(), net.Hosts()[2].ID())
+	require.NoError(t, err)
+	clock := newFakeClock()
+	serv := newTestServiceWithHost(ctx, t, net.Hosts()[0], false,
+		WithClock[*headertest.DummyHeader](clock),
+		WithMinPeerConnAge[*headertest.DummyHeader](time.Minute))
+	require.NoError(t, serv.Start(ctx))
+	_, err = net.ConnectPeers(net.Hosts()[0].ID(), net.Hosts()[1].ID())
+	require.NoError(t, err)
+
+	proofs := []fraud.Proof[*headertest.DummyHeader]{
+		fraudtest.NewValidProofAt[*headertest.DummyHeader](1),
+		fraudtest.NewValidProofAt[*headertest.DummyHeader](2),
+	}
+	process := func(i int) pubsub.ValidationResult {
+		msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, proofs[i])}}
+		return serv.processIncoming(ctx, proofs[i].Type(), net.Hosts()[i+1].ID(), msg)
+	}
+	// the peers just connected
+	for i := range proofs {
+		require.Equal(t, pubsub.ValidationIgnore, process(i))
+	}
+	_, err = serv.Get(ctx, fraudtest.DummyProofType)
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+
+	// the same proofs are accepted once the connections mature
+	clock.Advance(time.Minute)
+	for i := range proofs {
+		require.Equal(t, pubsub.ValidationAccept, process(i))
+	}
+}
+
+func TestService_processIncomingPanic(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	recorder := withTestTracer(t)
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewPanickingProof[*headertest.DummyHeader]()
+	msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, proof)}}
+	res := serv.processIncoming(ctx, proof.Type(), "peer", msg)
+	require.Equal(t, pubsub.ValidationReject, res)
+
+	// the header is fetched before validating the proof panics
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "process_proof", spans[1].Name())
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+	require.NotEmpty(t, spans[1].Events())
+
+	// the service is still operational and no locks are leaked
+	require.NoError(t, serv.AddVerifier(proof.Type(), func(fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+		return true, nil
+	}))
+	sub, err := serv.Subscribe(proof.Type())
+	require.NoError(t, err)
+	defer sub.Cancel()
+	_, err = serv.Get(ctx, proof.Type())
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+	require.Error(t, serv.Broadcast(ctx, proof))
+}
+
+func TestService_TracerProvider(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	global := withTestTracer(t)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	serv := newTestService(ctx, t, false, WithTracerProvider[*headertest.DummyHeader](provider))
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, proof)}}
+	require.Equal(t, pubsub.ValidationAccept, serv.processIncoming(ctx, proof.Type(), "peer", msg))
+
+	spans := recorder.Ended()
+	require.NotEmpty(t, spans)
+	require.Equal(t, "process_proof", spans[len(spans)-1].Name())
+	require.Empty(t, global.Ended())
+}
+
+func TestService_processIncomingStepSpans(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	serv := newTestService(ctx, t, false, WithTracerProvider[*headertest.DummyHeader](provider))
+	require.NoError(t, serv.Start(ctx))
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, serv.AddVerifier(proof.Type(), func(fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+		return true, nil
+	}))
+	msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, proof)}}
+	require.Equal(t, pubsub.ValidationAccept, serv.processIncoming(ctx, proof.Type(), "peer", msg))
+
+	spans := recorder.Ended()
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name()
+	}
+	require.Equal(t, []string{"fetch_header", "run_verifier", "validate", "store_proof", "process_proof"}, names)
+	parent := spans[4].SpanContext().SpanID()
+	for _, span := range spans[:4] {
+		require.Equal(t, parent, span.Parent().SpanID())
+	}
+}
+
+func TestService_BroadcastTracesStore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	serv := newTestService(ctx, t, false, WithTracerProvider[*headertest.DummyHeader](provider))
+	require.NoError(t, serv.Start(ctx))
+	require.NoError(t, serv.Broadcast(ctx, fraudtest.NewValidProof[*headertest.DummyHeader]()))
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	broadcast, process, store := spans["broadcast_proof"], spans["process_proof"], spans["store_proof"]
+	require.NotNil(t, broadcast)
+	require.NotNil(t, process)
+	require.NotNil(t, store)
+	require.Equal(t, broadcast.SpanContext().SpanID(), process.Parent().SpanID())
+	require.Equal(t, process.SpanContext().SpanID(), store.Parent().SpanID())
+	require.Equal(t, broadcast.SpanContext().TraceID(), store.SpanContext().TraceID())
+}
+
+func TestService_BroadcastNoTopicSpan(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	recorder := withTestTracer(t)
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	proof := &unknownProof{fraudtest.NewValidProof[*headertest.DummyHeader]()}
+	err := serv.Broadcast(ctx, proof)
+	require.ErrorIs(t, err, ErrTopicNotFound)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "broadcast_proof", spans[0].Name())
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Contains(t, spans[0].Attributes(), attribute.String("proof_type", "UnknownProof"))
+	require.Contains(t, spans[0].Attributes(), attribute.Int("block_height", 1))
+	require.Len(t, spans[0].Events(), 1)
+	require.Equal(t, "exception", spans[0].Events()[0].Name)
+}
+
+func TestService_PanickingVerifier(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, serv.AddVerifier(proof.Type(), func(fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+		panic("verifier panic")
+	}))
+	require.Error(t, serv.Broadcast(ctx, proof))
+
+	// subsequent operations taking the locks don't deadlock
+	require.NoError(t, serv.UnregisterProofType(proof.Type()))
+	require.NoError(t, serv.RegisterProofType(proof.Type()))
+	require.NoError(t, serv.AddVerifier(proof.Type(), func(fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+		return true, nil
+	}))
+	_, err := serv.Get(ctx, proof.Type())
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+	require.Equal(t, []fraud.ProofType{proof.Type()}, serv.Topics())
+}
+
 func TestService_SubscribeBroadcastValid(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	t.Cleanup(cancel)
@@ -58,6 +303,54 @@ func TestService_SubscribeBroadcastValid(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestService_WithoutHeadThreshold(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, WithoutHeadThreshold[*headertest.DummyHeader]())
+	// the head is never requested
+	serv.headGetter = nil
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	sub, err := serv.Subscribe(proof.Type())
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	require.NoError(t, serv.Broadcast(ctx, proof))
+	_, err = sub.Proof(ctx)
+	require.NoError(t, err)
+}
+
+func TestService_WithHeaderFetchers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	store := headertest.NewDummyStore(t)
+	var hotCalls, coldCalls int
+	hot := func(context.Context, uint64) (*headertest.DummyHeader, error) {
+		hotCalls++
+		return nil, errors.New("not found")
+	}
+	cold := func(ctx context.Context, height uint64) (*headertest.DummyHeader, error) {
+		coldCalls++
+		return store.GetByHeight(ctx, height)
+	}
+	serv := newTestService(ctx, t, false, WithHeaderFetchers[*headertest.DummyHeader](hot, cold))
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	sub, err := serv.Subscribe(proof.Type())
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	require.NoError(t, serv.Broadcast(ctx, proof))
+	_, err = sub.Proof(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, hotCalls)
+	require.Equal(t, 1, coldCalls)
+}
+
 func TestService_SubscribeBroadcastWithVerifiers(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	t.Cleanup(cancel)
@@ -74,6 +367,9 @@ func TestService_SubscribeBroadcastWithVerifiers(t *testing.T) {
 	require.Error(t, serv.AddVerifier(frd.Type(), func(fraudProof fraud.Proof[*headertest.DummyHeader]) (bool, error) {
 		return true, nil
 	}))
+	// test for error while adding a nil verifier
+	require.Error(t, serv.AddVerifier("OtherProof", nil))
+
 	sub, err := serv.Subscribe(frd.Type())
 	require.NoError(t, err)
 	defer sub.Cancel()
@@ -175,6 +471,104 @@ func TestService_ReGossiping(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestService_BroadcastAndWait(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(2)
+	require.NoError(t, err)
+
+	servA := newTestServiceWithHost(ctx, t, net.Hosts()[0], false)
+	require.NoError(t, servA.Start(ctx))
+	servB := newTestServiceWithHost(ctx, t, net.Hosts()[1], false)
+	require.NoError(t, servB.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	sub, err := servB.Subscribe(proof.Type())
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	// no peers to wait for within the timeout
+	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, time.Millisecond*100)
+	defer timeoutCancel()
+	err = servA.BroadcastAndWait(timeoutCtx, proof, 1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- servA.BroadcastAndWait(ctx, proof, 1)
+	}()
+
+	addrB := host.InfoFromHost(net.Hosts()[1])
+	require.NoError(t, net.Hosts()[0].Connect(ctx, *addrB))
+
+	require.NoError(t, <-errCh)
+	_, err = sub.Proof(ctx)
+	require.NoError(t, err)
+}
+
+func TestService_MessageIdFn(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(3)
+	require.NoError(t, err)
+
+	newService := func(h host.Host, tracer pubsub.EventTracer) *ProofService[*headertest.DummyHeader] {
+		// sign messages, so that the default message IDs of different publishers differ
+		ps, err := pubsub.NewFloodSub(ctx, h, pubsub.WithEventTracer(tracer))
+		require.NoError(t, err)
+		return newTestServiceWithPubSub(ctx, t, ps, h, false,
+			withDistinctMessages())
+	}
+	tracer := &countingTracer{}
+	servA := newService(net.Hosts()[0], &countingTracer{})
+	servB := newService(net.Hosts()[1], &countingTracer{})
+	servC := newService(net.Hosts()[2], tracer)
+
+	// connect A -> C <- B
+	addrC := host.InfoFromHost(net.Hosts()[2])
+	require.NoError(t, net.Hosts()[0].Connect(ctx, *addrC))
+	require.NoError(t, net.Hosts()[1].Connect(ctx, *addrC))
+
+	require.NoError(t, servA.Start(ctx))
+	require.NoError(t, servB.Start(ctx))
+	require.NoError(t, servC.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	sub, err := servC.Subscribe(proof.Type())
+	require.NoError(t, err)
+	defer sub.Cancel()
+	time.Sleep(time.Millisecond * 100)
+
+	require.NoError(t, servA.Broadcast(ctx, proof))
+	require.NoError(t, servB.Broadcast(ctx, proof))
+
+	_, err = sub.Proof(ctx)
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond * 100)
+
+	// the second copy is deduplicated by pubsub instead of being validated
+	require.EqualValues(t, 1, tracer.delivered.Load())
+	require.EqualValues(t, 1, tracer.duplicates.Load())
+	require.EqualValues(t, 0, tracer.rejected.Load())
+}
+
+type countingTracer struct {
+	delivered, duplicates, rejected atomic.Int64
+}
+
+func (c *countingTracer) Trace(evt *pubsub_pb.TraceEvent) {
+	switch evt.GetType() {
+	case pubsub_pb.TraceEvent_DELIVER_MESSAGE:
+		c.delivered.Add(1)
+	case pubsub_pb.TraceEvent_DUPLICATE_MESSAGE:
+		c.duplicates.Add(1)
+	case pubsub_pb.TraceEvent_REJECT_MESSAGE:
+		c.rejected.Add(1)
+	}
+}
+
 func TestService_Get(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	t.Cleanup(cancel)
@@ -200,6 +594,75 @@ func TestService_Get(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestService_GetByHeightRange(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+	_, err := serv.GetByHeightRange(ctx, fraudtest.DummyProofType, 1, 10)
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+
+	for _, height := range []uint64{5, 2, 8, 3} {
+		_, err = serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](height))
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		from, to uint64
+		heights  []uint64
+	}{
+		{from: 1, to: 10, heights: []uint64{2, 3, 5, 8}},
+		{from: 3, to: 5, heights: []uint64{3, 5}},
+		{from: 8, to: 8, heights: []uint64{8}},
+		{from: 6, to: 7},
+		{from: 9, to: 100},
+	}
+	for _, tt := range tests {
+		proofs, err := serv.GetByHeightRange(ctx, fraudtest.DummyProofType, tt.from, tt.to)
+		if len(tt.heights) == 0 {
+			require.ErrorIs(t, err, datastore.ErrNotFound)
+			continue
+		}
+		require.NoError(t, err)
+		heights := make([]uint64, 0, len(proofs))
+		for _, proof := range proofs {
+			heights = append(heights, proof.Height())
+		}
+		require.Equal(t, tt.heights, heights, "range [%d, %d]", tt.from, tt.to)
+	}
+
+	_, err = serv.GetByHeightRange(ctx, fraudtest.DummyProofType, 5, 4)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, datastore.ErrNotFound)
+}
+
+func TestService_GetRaw(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	_, err := serv.GetRaw(ctx, fraudtest.DummyProofType)
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+
+	for height := uint64(1); height <= 3; height++ {
+		_, err = serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](height))
+		require.NoError(t, err)
+	}
+
+	raw, err := serv.GetRaw(ctx, fraudtest.DummyProofType)
+	require.NoError(t, err)
+	proofs, err := serv.Get(ctx, fraudtest.DummyProofType)
+	require.NoError(t, err)
+	marshaled := make([][]byte, 0, len(proofs))
+	for _, proof := range proofs {
+		marshaled = append(marshaled, mustMarshal(t, proof))
+	}
+	require.ElementsMatch(t, marshaled, raw)
+}
+
 func TestService_Sync(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	t.Cleanup(cancel)
@@ -228,51 +691,1348 @@ func TestService_Sync(t *testing.T) {
 	require.NoError(t, err)
 }
 
-func newTestService(ctx context.Context, t *testing.T, enabledSyncer bool) *ProofService[*headertest.DummyHeader] {
-	net, err := mocknet.FullMeshLinked(1)
+func TestService_SyncProgress(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(2)
 	require.NoError(t, err)
-	return newTestServiceWithHost(ctx, t, net.Hosts()[0], enabledSyncer)
+
+	servA := newTestServiceWithHost(ctx, t, net.Hosts()[0], false)
+	require.NoError(t, servA.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, servA.Broadcast(ctx, proof))
+
+	progress := make(chan int, 1)
+	servB := newTestServiceWithHost(ctx, t, net.Hosts()[1], true,
+		WithSyncProgress[*headertest.DummyHeader](func(proofType fraud.ProofType, fetched int) {
+			require.Equal(t, proof.Type(), proofType)
+			progress <- fetched
+		}))
+	require.NoError(t, servB.Start(ctx))
+
+	addrB := host.InfoFromHost(net.Hosts()[1])
+	require.NoError(t, net.Hosts()[0].Connect(ctx, *addrB))
+
+	select {
+	case fetched := <-progress:
+		require.Equal(t, 1, fetched)
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
 }
 
-func newTestServiceWithHost(
-	ctx context.Context,
-	t *testing.T,
-	host host.Host,
-	enabledSyncer bool,
-) *ProofService[*headertest.DummyHeader] {
-	ps, err := pubsub.NewFloodSub(ctx, host, pubsub.WithMessageSignaturePolicy(pubsub.StrictNoSign))
+func TestService_SyncKnownReencoded(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(2)
 	require.NoError(t, err)
 
-	store := headertest.NewDummyStore(t)
-	serv := NewProofService[*headertest.DummyHeader](
-		ps,
-		host,
-		func(ctx context.Context, u uint64) (*headertest.DummyHeader, error) {
-			return store.GetByHeight(ctx, u)
-		},
-		func(ctx context.Context) (*headertest.DummyHeader, error) {
-			return store.Head(ctx)
-		},
-		unmarshaler,
-		sync.MutexWrap(datastore.NewMapDatastore()),
-		enabledSyncer,
-		"private",
-	)
+	servA := newTestServiceWithHost(ctx, t, net.Hosts()[0], false)
+	require.NoError(t, servA.Start(ctx))
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	_, err = servA.Ingest(ctx, proof)
+	require.NoError(t, err)
 
-	t.Cleanup(func() {
-		err := serv.Stop(ctx)
-		if err != nil {
-			t.Fatal(err)
-		}
-	})
-	return serv
+	fetched := make(chan int, 1)
+	servB := newTestServiceWithHost(ctx, t, net.Hosts()[1], true,
+		WithSyncProgress[*headertest.DummyHeader](func(_ fraud.ProofType, n int) {
+			fetched <- n
+		}))
+	require.NoError(t, servB.Start(ctx))
+	// servB already holds the proof, but encoded differently than servA serves it
+	reencoded := []byte(`{"Panics":false,"Valid":true}`)
+	require.NotEqual(t, mustMarshal(t, proof), reencoded)
+	key := servB.keyHasher(proof.HeaderHash())
+	require.NoError(t, put(ctx, servB.storeFor(proof.Type()), key, reencoded))
+	sub, err := servB.Subscribe(proof.Type())
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	_, err = net.ConnectPeers(net.Hosts()[0].ID(), net.Hosts()[1].ID())
+	require.NoError(t, err)
+	select {
+	case n := <-fetched:
+		require.Equal(t, 1, n)
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+
+	// the proof is known, so it is neither delivered nor stored again
+	waitCtx, waitCancel := context.WithTimeout(ctx, time.Millisecond*100)
+	defer waitCancel()
+	_, err = sub.Proof(waitCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	entries, err := query(ctx, servB.storeFor(proof.Type()), q.Query{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, reencoded, entries[0].Value)
 }
 
-var unmarshaler = &fraud.MultiUnmarshaler[*headertest.DummyHeader]{
-	Unmarshalers: map[fraud.ProofType]func([]byte) (fraud.Proof[*headertest.DummyHeader], error){
-		fraudtest.DummyProofType: func(data []byte) (fraud.Proof[*headertest.DummyHeader], error) {
-			proof := &fraudtest.DummyProof[*headertest.DummyHeader]{}
-			return proof, proof.UnmarshalBinary(data)
-		},
-	},
+func TestService_RawFallback(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(2)
+	require.NoError(t, err)
+
+	servA := newTestServiceWithHost(ctx, t, net.Hosts()[0], false,
+		WithRawFallback[*headertest.DummyHeader](true))
+	require.NoError(t, servA.Start(ctx))
+	raw := &fraud.RawProof[*headertest.DummyHeader]{ProofType: "UnknownProof", Data: []byte("opaque")}
+	status, err := servA.Ingest(ctx, raw)
+	require.NoError(t, err)
+	require.Equal(t, ProcessAccepted, status)
+
+	proofs, err := servA.Get(ctx, "UnknownProof")
+	require.NoError(t, err)
+	require.Len(t, proofs, 1)
+	require.IsType(t, raw, proofs[0])
+	require.Equal(t, raw.Data, proofs[0].(*fraud.RawProof[*headertest.DummyHeader]).Data)
+
+	// the raw proof is served to peers as is
+	servB := newTestServiceWithHost(ctx, t, net.Hosts()[1], false)
+	_, err = net.ConnectPeers(net.Hosts()[0].ID(), net.Hosts()[1].ID())
+	require.NoError(t, err)
+	resp, err := servB.requestProofs(ctx, protocolID("private"), net.Hosts()[0].ID(), []string{"UnknownProof"})
+	require.NoError(t, err)
+	require.Len(t, resp, 1)
+	require.Equal(t, [][]byte{raw.Data}, resp[0].Value)
+
+	// without the fallback, unknown types are not stored
+	servC := newTestService(ctx, t, false)
+	status, err = servC.Ingest(ctx, raw)
+	require.Error(t, err)
+	require.NotEqual(t, ProcessAccepted, status)
+}
+
+func TestService_ServeWithSyncerDisabled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshConnected(3)
+	require.NoError(t, err)
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	serving := newTestServiceWithHost(ctx, t, net.Hosts()[0], false)
+	notServing := newTestServiceWithHost(ctx, t, net.Hosts()[1], false, WithServeDisabled[*headertest.DummyHeader]())
+	for _, serv := range []*ProofService[*headertest.DummyHeader]{serving, notServing} {
+		require.NoError(t, serv.Start(ctx))
+		require.NoError(t, serv.Broadcast(ctx, proof))
+	}
+
+	requester := newTestServiceWithHost(ctx, t, net.Hosts()[2], false)
+	resp, err := requester.requestProofs(ctx, protocolID("private"), net.Hosts()[0].ID(), []string{string(proof.Type())})
+	require.NoError(t, err)
+	require.Len(t, resp, 1)
+	require.Equal(t, [][]byte{mustMarshal(t, proof)}, resp[0].Value)
+
+	_, err = requester.requestProofs(ctx, protocolID("private"), net.Hosts()[1].ID(), []string{string(proof.Type())})
+	require.Error(t, err)
+}
+
+func TestService_ServeMaxBytes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshConnected(2)
+	require.NoError(t, err)
+	proofSize := len(mustMarshal(t, fraudtest.NewValidProofAt[*headertest.DummyHeader](1)))
+	serving := newTestServiceWithHost(ctx, t, net.Hosts()[0], false,
+		WithServeMaxBytes[*headertest.DummyHeader](proofSize*3))
+	require.NoError(t, serving.Start(ctx))
+	for height := uint64(1); height <= 9; height++ {
+		_, err = serving.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](height))
+		require.NoError(t, err)
+	}
+
+	requester := newTestServiceWithHost(ctx, t, net.Hosts()[1], false)
+	req := &pb.FraudMessageRequest{RequestedProofType: []string{string(fraudtest.DummyProofType)}}
+	resp, err := requester.request(ctx, protocolID("private"), net.Hosts()[0].ID(), req)
+	require.NoError(t, err)
+	require.Len(t, resp.Proofs, 1)
+	require.Len(t, resp.Proofs[0].Value, 3)
+	last := fraudtest.NewValidProofAt[*headertest.DummyHeader](3)
+	require.Equal(t, &pb.ProofResponse{
+		Type:  string(fraudtest.DummyProofType),
+		Value: [][]byte{inventoryItem(last.Height(), last.HeaderHash())},
+	}, resp.Cursor)
+
+	// the capped response is continued with its cursor
+	req.Cursor = resp.Cursor
+	resp, err = requester.request(ctx, protocolID("private"), net.Hosts()[0].ID(), req)
+	require.NoError(t, err)
+	require.Len(t, resp.Proofs, 1)
+	require.Equal(t, [][]byte{
+		mustMarshal(t, fraudtest.NewValidProofAt[*headertest.DummyHeader](4)),
+		mustMarshal(t, fraudtest.NewValidProofAt[*headertest.DummyHeader](5)),
+		mustMarshal(t, fraudtest.NewValidProofAt[*headertest.DummyHeader](6)),
+	}, resp.Proofs[0].Value)
+
+	proofs, err := requester.requestProofs(ctx, protocolID("private"), net.Hosts()[0].ID(),
+		[]string{string(fraudtest.DummyProofType)})
+	require.NoError(t, err)
+	require.Len(t, proofs, 1)
+	require.Len(t, proofs[0].Value, 9)
+
+	// proofs over the cap on their own are still served one by one
+	serving.serveMaxBytes = proofSize / 2
+	proofs, err = requester.requestProofs(ctx, protocolID("private"), net.Hosts()[0].ID(),
+		[]string{string(fraudtest.DummyProofType)})
+	require.NoError(t, err)
+	require.Len(t, proofs, 1)
+	require.Len(t, proofs[0].Value, 9)
+}
+
+func TestService_RequestAllBounded(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshConnected(2)
+	require.NoError(t, err)
+	requester := newTestServiceWithHost(ctx, t, net.Hosts()[1], false)
+	proofType := string(fraudtest.DummyProofType)
+	proofAt := func(height uint64) []byte {
+		return mustMarshal(t, fraudtest.NewValidProofAt[*headertest.DummyHeader](height))
+	}
+	cursorAt := func(height uint64) *pb.ProofResponse {
+		proof := fraudtest.NewValidProofAt[*headertest.DummyHeader](height)
+		return &pb.ProofResponse{Type: proofType, Value: [][]byte{inventoryItem(proof.Height(), proof.HeaderHash())}}
+	}
+	// serve responds to every request of the requester with the response for its cursor
+	serve := func(respond func(cursor *pb.ProofResponse) *pb.FraudMessageResponse) {
+		net.Hosts()[0].SetStreamHandler(protocolID("private"), func(stream network.Stream) {
+			req := &pb.FraudMessageRequest{}
+			_, err := serde.Read(stream, req)
+			assert.NoError(t, err)
+			_, err = serde.Write(stream, respond(req.Cursor))
+			assert.NoError(t, err)
+			assert.NoError(t, stream.Close())
+		})
+	}
+	request := func() ([]*pb.ProofResponse, error) {
+		return requester.requestProofs(ctx, protocolID("private"), net.Hosts()[0].ID(), []string{proofType})
+	}
+
+	// the cursor never moves
+	serve(func(*pb.ProofResponse) *pb.FraudMessageResponse {
+		return &pb.FraudMessageResponse{
+			Proofs: []*pb.ProofResponse{{Type: proofType, Value: [][]byte{proofAt(1)}}},
+			Cursor: cursorAt(1),
+		}
+	})
+	_, err = request()
+	require.Error(t, err)
+
+	// the cursor is before the served proofs
+	serve(func(*pb.ProofResponse) *pb.FraudMessageResponse {
+		return &pb.FraudMessageResponse{
+			Proofs: []*pb.ProofResponse{{Type: proofType, Value: [][]byte{proofAt(5)}}},
+			Cursor: cursorAt(1),
+		}
+	})
+	_, err = request()
+	require.Error(t, err)
+
+	// the cursor moves forever
+	serve(func(cursor *pb.ProofResponse) *pb.FraudMessageResponse {
+		height := uint64(1)
+		if cursor != nil {
+			prev, _, err := parseInventoryItem(cursor.Value[0])
+			assert.NoError(t, err)
+			height = prev + 1
+		}
+		return &pb.FraudMessageResponse{
+			Proofs: []*pb.ProofResponse{{Type: proofType, Value: [][]byte{proofAt(height)}}},
+			Cursor: cursorAt(height),
+		}
+	})
+	_, err = request()
+	require.Error(t, err)
+
+	// duplicates are dropped
+	serve(func(cursor *pb.ProofResponse) *pb.FraudMessageResponse {
+		if cursor != nil {
+			return &pb.FraudMessageResponse{
+				Proofs: []*pb.ProofResponse{{Type: proofType, Value: [][]byte{proofAt(1), proofAt(2)}}},
+			}
+		}
+		return &pb.FraudMessageResponse{
+			Proofs: []*pb.ProofResponse{{Type: proofType, Value: [][]byte{proofAt(1)}}},
+			Cursor: cursorAt(1),
+		}
+	})
+	proofs, err := request()
+	require.NoError(t, err)
+	require.Equal(t, []*pb.ProofResponse{{Type: proofType, Value: [][]byte{proofAt(1), proofAt(2)}}}, proofs)
+}
+
+func TestService_RespondMalformedCursor(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshConnected(2)
+	require.NoError(t, err)
+	serving := newTestServiceWithHost(ctx, t, net.Hosts()[0], false)
+	require.NoError(t, serving.Start(ctx))
+	_, err = serving.Ingest(ctx, fraudtest.NewValidProof[*headertest.DummyHeader]())
+	require.NoError(t, err)
+
+	requester := newTestServiceWithHost(ctx, t, net.Hosts()[1], false)
+	for _, cursor := range []*pb.ProofResponse{
+		{Type: string(fraudtest.DummyProofType), Value: [][]byte{[]byte("short")}},
+		{Type: "UnknownProof"},
+	} {
+		_, err = requester.request(ctx, protocolID("private"), net.Hosts()[0].ID(), &pb.FraudMessageRequest{
+			RequestedProofType: []string{string(fraudtest.DummyProofType)},
+			Cursor:             cursor,
+		})
+		require.Error(t, err)
+	}
+}
+
+func TestService_BroadcastN(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshConnected(3)
+	require.NoError(t, err)
+	servs := make([]*ProofService[*headertest.DummyHeader], 3)
+	for i, h := range net.Hosts() {
+		servs[i] = newTestServiceWithHost(ctx, t, h, false)
+		require.NoError(t, servs[i].Start(ctx))
+	}
+	// only the peers subscribed to the topic count
+	for _, serv := range servs[1:] {
+		sub, err := serv.Subscribe(fraudtest.DummyProofType)
+		require.NoError(t, err)
+		defer sub.Cancel()
+	}
+	topic := servs[0].joinedTopics()[fraudtest.DummyProofType]
+	require.Eventually(t, func() bool {
+		return len(topic.ListPeers()) == 2
+	}, time.Second, time.Millisecond*10)
+
+	n, err := servs[0].BroadcastN(ctx, fraudtest.NewValidProof[*headertest.DummyHeader]())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
+
+func TestService_PrioritizedValidation(t *testing.T) {
+	for _, prioritized := range []bool{false, true} {
+		t.Run(fmt.Sprintf("prioritized %t", prioritized), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			t.Cleanup(cancel)
+
+			net, err := mocknet.FullMeshConnected(2)
+			require.NoError(t, err)
+			hostA, hostB := net.Hosts()[0], net.Hosts()[1]
+			// the validation throttle of servA fits a single message
+			psA, err := pubsub.NewFloodSub(ctx, hostA,
+				pubsub.WithMessageSignaturePolicy(pubsub.StrictNoSign), pubsub.WithValidateThrottle(1))
+			require.NoError(t, err)
+			var opts []Option[*headertest.DummyHeader]
+			if prioritized {
+				opts = append(opts, WithPrioritizedValidation[*headertest.DummyHeader]())
+			}
+			servA := newTestServiceWithPubSub(ctx, t, psA, hostA, false, opts...)
+			require.NoError(t, servA.Start(ctx))
+			servB := newTestServiceWithHost(ctx, t, hostB, false)
+			require.NoError(t, servB.Start(ctx))
+
+			// a data message exhausts the validation throttle of servA until its validation is done
+			validating, unblock := make(chan struct{}), make(chan struct{})
+			t.Cleanup(func() { close(unblock) })
+			require.NoError(t, psA.RegisterTopicValidator("data",
+				func(context.Context, peer.ID, *pubsub.Message) pubsub.ValidationResult {
+					close(validating)
+					<-unblock
+					return pubsub.ValidationAccept
+				}))
+			dataA, err := psA.Join("data")
+			require.NoError(t, err)
+			dataSub, err := dataA.Subscribe()
+			require.NoError(t, err)
+			defer dataSub.Cancel()
+			dataB, err := servB.pubsub.Join("data")
+			require.NoError(t, err)
+
+			sub, err := servA.Subscribe(fraudtest.DummyProofType)
+			require.NoError(t, err)
+			defer sub.Cancel()
+			topic := servB.joinedTopics()[fraudtest.DummyProofType]
+			require.Eventually(t, func() bool {
+				return len(topic.ListPeers()) == 1 && len(dataB.ListPeers()) == 1
+			}, time.Second, time.Millisecond*10)
+
+			require.NoError(t, dataB.Publish(ctx, []byte("data")))
+			<-validating
+			proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+			require.NoError(t, servB.Broadcast(ctx, proof))
+
+			proofCtx, proofCancel := context.WithTimeout(ctx, time.Millisecond*200)
+			defer proofCancel()
+			got, err := sub.Proof(proofCtx)
+			if !prioritized {
+				require.ErrorIs(t, err, context.DeadlineExceeded)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, proof.HeaderHash(), got.HeaderHash())
+		})
+	}
+}
+
+func TestService_BroadcastTo(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshConnected(3)
+	require.NoError(t, err)
+
+	servs := make([]*ProofService[*headertest.DummyHeader], 3)
+	subs := make([]fraud.Subscription[*headertest.DummyHeader], 3)
+	for i, h := range net.Hosts() {
+		servs[i] = newTestServiceWithHost(ctx, t, h, false)
+		require.NoError(t, servs[i].Start(ctx))
+		subs[i], err = servs[i].Subscribe(fraudtest.DummyProofType)
+		require.NoError(t, err)
+		defer subs[i].Cancel()
+	}
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, servs[0].BroadcastTo(ctx, proof, net.Hosts()[1].ID()))
+
+	got, err := subs[1].Proof(ctx)
+	require.NoError(t, err)
+	require.Equal(t, proof.HeaderHash(), got.HeaderHash())
+	_, err = servs[1].Get(ctx, proof.Type())
+	require.NoError(t, err)
+
+	// neither the sender nor the other peer receive the proof
+	for _, i := range []int{0, 2} {
+		waitCtx, waitCancel := context.WithTimeout(ctx, time.Millisecond*100)
+		_, err = subs[i].Proof(waitCtx)
+		waitCancel()
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		_, err = servs[i].Get(ctx, proof.Type())
+		require.ErrorIs(t, err, datastore.ErrNotFound)
+	}
+}
+
+func TestService_BroadcastToProcessedAsPusher(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshConnected(2)
+	require.NoError(t, err)
+	sender := newTestServiceWithHost(ctx, t, net.Hosts()[0], false)
+	require.NoError(t, sender.Start(ctx))
+	recipient := newTestServiceWithHost(ctx, t, net.Hosts()[1], false,
+		WithRawFallback[*headertest.DummyHeader](true))
+	require.NoError(t, recipient.Start(ctx))
+
+	// raw proofs pushed by peers can't be validated, so they are not accepted
+	raw := &fraud.RawProof[*headertest.DummyHeader]{ProofType: "UnknownProof", Data: []byte("opaque")}
+	require.NoError(t, sender.BroadcastTo(ctx, raw, net.Hosts()[1].ID()))
+	_, err = recipient.GetRaw(ctx, raw.Type())
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+
+	// the pusher of an invalid proof is blacklisted, rather than the recipient itself
+	invalid := fraudtest.NewInvalidProof[*headertest.DummyHeader]()
+	require.NoError(t, sender.BroadcastTo(ctx, invalid, net.Hosts()[1].ID()))
+	blacklisted := recipient.BlacklistedPeers()
+	require.Len(t, blacklisted, 1)
+	require.Equal(t, net.Hosts()[0].ID(), blacklisted[0].Peer)
+
+	// further pushes of the blacklisted peer are ignored
+	valid := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, sender.BroadcastTo(ctx, valid, net.Hosts()[1].ID()))
+	_, err = recipient.Get(ctx, valid.Type())
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+}
+
+func TestService_PushLimits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshConnected(2)
+	require.NoError(t, err)
+	sender := newTestServiceWithHost(ctx, t, net.Hosts()[0], false)
+	require.NoError(t, sender.Start(ctx))
+	recipient := newTestServiceWithHost(ctx, t, net.Hosts()[1], false)
+	require.NoError(t, recipient.Start(ctx))
+	senderID := net.Hosts()[0].ID()
+
+	// too many proofs in a single push
+	pushed := &pb.ProofResponse{Type: string(fraudtest.DummyProofType)}
+	for height := uint64(1); height <= maxPushedProofs+1; height++ {
+		pushed.Value = append(pushed.Value, mustMarshal(t, fraudtest.NewValidProofAt[*headertest.DummyHeader](height)))
+	}
+	_, err = sender.request(ctx, protocolID("private"), net.Hosts()[1].ID(),
+		&pb.FraudMessageRequest{Pushed: []*pb.ProofResponse{pushed}})
+	require.Error(t, err)
+
+	// too many concurrent pushes
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	recipient.pushesLk.Lock()
+	recipient.pushes[senderID] = maxPeerPushes
+	recipient.pushesLk.Unlock()
+	require.Error(t, sender.BroadcastTo(ctx, proof, net.Hosts()[1].ID()))
+	_, err = recipient.Get(ctx, proof.Type())
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+	recipient.pushesLk.Lock()
+	delete(recipient.pushes, senderID)
+	recipient.pushesLk.Unlock()
+
+	// the pusher of a proof rejected for any reason is blacklisted
+	above := fraudtest.NewValidProofAt[*headertest.DummyHeader](100)
+	require.NoError(t, sender.BroadcastTo(ctx, above, net.Hosts()[1].ID()))
+	blacklisted := recipient.BlacklistedPeers()
+	require.Len(t, blacklisted, 1)
+	require.Equal(t, senderID, blacklisted[0].Peer)
+	require.Equal(t, ReasonRejectedPush, blacklisted[0].Reason)
+}
+
+func TestService_SyncPeerSelector(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(3)
+	require.NoError(t, err)
+
+	servA := newTestServiceWithHost(ctx, t, net.Hosts()[0], false)
+	require.NoError(t, servA.Start(ctx))
+	require.NoError(t, servA.Broadcast(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](1)))
+	servC := newTestServiceWithHost(ctx, t, net.Hosts()[2], false)
+	require.NoError(t, servC.Start(ctx))
+	require.NoError(t, servC.Broadcast(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](2)))
+
+	selected := make(chan struct{}, 2)
+	servB := newTestServiceWithHost(ctx, t, net.Hosts()[1], true,
+		WithSyncPeerSelector[*headertest.DummyHeader](func(context.Context) []peer.ID {
+			selected <- struct{}{}
+			return []peer.ID{net.Hosts()[0].ID()}
+		}))
+	require.NoError(t, servB.Start(ctx))
+
+	addrB := host.InfoFromHost(net.Hosts()[1])
+	require.NoError(t, net.Hosts()[2].Connect(ctx, *addrB))
+	require.NoError(t, net.Hosts()[0].Connect(ctx, *addrB))
+	for i := 0; i < 2; i++ {
+		select {
+		case <-selected:
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		}
+	}
+
+	// only the proof of the selected peer is synced
+	require.Eventually(t, func() bool {
+		proofs, err := servB.Get(ctx, fraudtest.DummyProofType)
+		return err == nil && len(proofs) == 1 && proofs[0].Height() == 1
+	}, time.Second*4, time.Millisecond*10)
+}
+
+func TestService_ConnectReconciliation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(2)
+	require.NoError(t, err)
+
+	reconcile := WithConnectReconciliation[*headertest.DummyHeader](true)
+	servA := newTestServiceWithHost(ctx, t, net.Hosts()[0], false, reconcile)
+	require.NoError(t, servA.Start(ctx))
+	// servB has its head at height 1, so that proofs above height 2 are above its head threshold
+	servB := newTestServiceWithHost(ctx, t, net.Hosts()[1], false, reconcile,
+		WithHeadThresholdFor[*headertest.DummyHeader](fraudtest.DummyProofType, 1))
+	servB.headGetter = func(ctx context.Context) (*headertest.DummyHeader, error) {
+		return servB.headerGetter(ctx, 1)
+	}
+	require.NoError(t, servB.Start(ctx))
+
+	// both have a proof, but only servA has the others
+	shared := fraudtest.NewValidProofAt[*headertest.DummyHeader](1)
+	lacked := fraudtest.NewValidProofAt[*headertest.DummyHeader](2)
+	ahead := fraudtest.NewValidProofAt[*headertest.DummyHeader](5)
+	for _, proof := range []fraud.Proof[*headertest.DummyHeader]{shared, lacked, ahead} {
+		_, err = servA.Ingest(ctx, proof)
+		require.NoError(t, err)
+	}
+	_, err = servB.Ingest(ctx, shared)
+	require.NoError(t, err)
+
+	sub, err := servB.Subscribe(lacked.Type())
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	// the sync is disabled, so servB can only get the proof by reconciliation
+	_, err = net.ConnectPeers(net.Hosts()[1].ID(), net.Hosts()[0].ID())
+	require.NoError(t, err)
+
+	proof, err := sub.Proof(ctx)
+	require.NoError(t, err)
+	require.Equal(t, lacked.Height(), proof.Height())
+	known, err := servB.Known(ctx, lacked.Type(), lacked.HeaderHash())
+	require.NoError(t, err)
+	require.True(t, known)
+	// pulled proofs are checked against the head threshold
+	time.Sleep(time.Millisecond * 100)
+	known, err = servB.Known(ctx, ahead.Type(), ahead.HeaderHash())
+	require.NoError(t, err)
+	require.False(t, known)
+
+	// only the proofs of the requested hashes are served
+	resp, err := servB.request(ctx, protocolID(servB.networkID), net.Hosts()[0].ID(), &pb.FraudMessageRequest{
+		RequestedProofType: []string{lacked.Type().String()},
+		RequestedHashes: []*pb.ProofResponse{
+			{Type: lacked.Type().String(), Value: [][]byte{lacked.HeaderHash(), []byte("unknown")}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Proofs, 1)
+	require.Equal(t, [][]byte{mustMarshal(t, lacked)}, resp.Proofs[0].Value)
+}
+
+func TestService_ReconcileSplitStore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshConnected(2)
+	require.NoError(t, err)
+	servA := newTestServiceWithHost(ctx, t, net.Hosts()[0], false,
+		WithConnectReconciliation[*headertest.DummyHeader](true))
+	require.NoError(t, servA.Start(ctx))
+	var pulls atomic.Int32
+	net.Hosts()[0].SetStreamHandler(protocolID("private"), func(stream network.Stream) {
+		pulls.Add(1)
+		servA.handleFraudMessageRequest(stream)
+	})
+	readDS := sync.MutexWrap(datastore.NewMapDatastore())
+	servB := newTestServiceWithHost(ctx, t, net.Hosts()[1], false,
+		WithSplitStore[*headertest.DummyHeader](readDS, sync.MutexWrap(datastore.NewMapDatastore())))
+	require.NoError(t, servB.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	_, err = servA.Ingest(ctx, proof)
+	require.NoError(t, err)
+	// servB holds the proof in the replica it reads from only
+	key := servB.StorageKey(proof.Type(), proof.HeaderHash())
+	require.NoError(t, readDS.Put(ctx, key, mustMarshal(t, proof)))
+
+	require.NoError(t, servB.reconcile(ctx, net.Hosts()[0].ID()))
+	require.Zero(t, pulls.Load())
+}
+
+func TestService_SyncAboveHeadThreshold(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(2)
+	require.NoError(t, err)
+
+	servA := newTestServiceWithHost(ctx, t, net.Hosts()[0], false)
+	require.NoError(t, servA.Start(ctx))
+
+	proof := fraudtest.NewValidProofAt[*headertest.DummyHeader](5)
+	require.NoError(t, servA.Broadcast(ctx, proof))
+
+	// servB is catching up with its head at height 1, so the proof is above its head threshold
+	servB := newTestServiceWithHost(ctx, t, net.Hosts()[1], true,
+		WithHeadThresholdFor[*headertest.DummyHeader](proof.Type(), 1))
+	servB.headGetter = func(ctx context.Context) (*headertest.DummyHeader, error) {
+		return servB.headerGetter(ctx, 1)
+	}
+	require.NoError(t, servB.Start(ctx))
+	status, _ := servB.DryRun(ctx, proof)
+	require.Equal(t, ProcessRejected, status)
+
+	addrB := host.InfoFromHost(net.Hosts()[1])
+	require.NoError(t, net.Hosts()[0].Connect(ctx, *addrB))
+
+	require.Eventually(t, func() bool {
+		proofs, err := servB.Get(ctx, proof.Type())
+		return err == nil && len(proofs) == 1 && proofs[0].Height() == proof.Height()
+	}, time.Second*4, time.Millisecond*10)
+}
+
+func TestService_Errors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+
+	_, err := serv.Subscribe(proof.Type())
+	require.ErrorIs(t, err, ErrServiceNotRunning)
+	err = serv.Broadcast(ctx, proof)
+	require.ErrorIs(t, err, ErrServiceNotRunning)
+
+	require.NoError(t, serv.Start(ctx))
+
+	_, err = serv.Subscribe("UnknownProof")
+	require.ErrorIs(t, err, ErrTopicNotFound)
+	err = serv.Broadcast(ctx, &unknownProof{proof})
+	require.ErrorIs(t, err, ErrTopicNotFound)
+}
+
+func TestService_Known(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	known, err := serv.Known(ctx, proof.Type(), proof.HeaderHash())
+	require.NoError(t, err)
+	require.False(t, known)
+
+	require.NoError(t, serv.Broadcast(ctx, proof))
+
+	known, err = serv.Known(ctx, proof.Type(), proof.HeaderHash())
+	require.NoError(t, err)
+	require.True(t, known)
+}
+
+func TestService_KnownRemote(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshConnected(2)
+	require.NoError(t, err)
+
+	servA := newTestServiceWithHost(ctx, t, net.Hosts()[0], false)
+	require.NoError(t, servA.Start(ctx))
+	servB := newTestServiceWithHost(ctx, t, net.Hosts()[1], false,
+		WithRemoteKnownCheck[*headertest.DummyHeader](true))
+	require.NoError(t, servB.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, servA.put(ctx, proof.Type(), servA.keyHasher(proof.HeaderHash()), mustMarshal(t, proof)))
+
+	known, err := servB.Known(ctx, proof.Type(), proof.HeaderHash())
+	require.NoError(t, err)
+	require.True(t, known)
+}
+
+func TestService_InvalidNames(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(1)
+	require.NoError(t, err)
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+
+	serv := NewProofService[*headertest.DummyHeader](nil, net.Hosts()[0], nil, nil, unmarshaler, ds, false, "")
+	require.Error(t, serv.Start(ctx))
+
+	slashed := &fraud.MultiUnmarshaler[*headertest.DummyHeader]{
+		Unmarshalers: map[fraud.ProofType]func([]byte) (fraud.Proof[*headertest.DummyHeader], error){
+			"bad/type": unmarshaler.Unmarshalers[fraudtest.DummyProofType],
+		},
+	}
+	serv = NewProofService[*headertest.DummyHeader](nil, net.Hosts()[0], nil, nil, slashed, ds, false, "private")
+	require.Error(t, serv.Start(ctx))
+}
+
+func TestService_TopicScore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshConnected(2)
+	require.NoError(t, err)
+	params := &pubsub.TopicScoreParams{
+		TopicWeight:                    1,
+		InvalidMessageDeliveriesWeight: -1,
+		InvalidMessageDeliveriesDecay:  0.5,
+		SkipAtomicValidation:           true,
+	}
+	servs := make([]*ProofService[*headertest.DummyHeader], 2)
+	for i, h := range net.Hosts() {
+		ps, err := pubsub.NewGossipSub(ctx, h,
+			pubsub.WithMessageSignaturePolicy(pubsub.StrictNoSign),
+			pubsub.WithPeerScore(
+				&pubsub.PeerScoreParams{
+					Topics:           make(map[string]*pubsub.TopicScoreParams),
+					AppSpecificScore: func(peer.ID) float64 { return 0 },
+					DecayInterval:    time.Second,
+					DecayToZero:      0.01,
+				},
+				&pubsub.PeerScoreThresholds{GossipThreshold: -10, PublishThreshold: -50, GraylistThreshold: -80},
+			))
+		require.NoError(t, err)
+		servs[i] = newTestServiceWithPubSub(ctx, t, ps, h, false,
+			WithTopicScore[*headertest.DummyHeader](fraudtest.DummyProofType, params))
+		require.NoError(t, servs[i].Start(ctx))
+	}
+
+	sub, err := servs[1].Subscribe(fraudtest.DummyProofType)
+	require.NoError(t, err)
+	defer sub.Cancel()
+	require.Eventually(t, func() bool {
+		return len(servs[0].joinedTopics()[fraudtest.DummyProofType].ListPeers()) == 1
+	}, time.Second, time.Millisecond*10)
+	require.NoError(t, servs[0].Broadcast(ctx, fraudtest.NewValidProof[*headertest.DummyHeader]()))
+	_, err = sub.Proof(ctx)
+	require.NoError(t, err)
+
+	// scoring topics requires the router to support it
+	serv := newTestService(ctx, t, false, WithTopicScore[*headertest.DummyHeader](fraudtest.DummyProofType, params))
+	require.Error(t, serv.Start(ctx))
+}
+
+func TestService_MaxProofTypes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	// all the supported types are registered on Start
+	serv := newTestService(ctx, t, false,
+		WithMaxProofTypes[*headertest.DummyHeader](2),
+		withProofTypes(fraudtest.DummyProofType, "Another", "OneTooMany"))
+	require.ErrorIs(t, serv.Start(ctx), ErrTooManyProofTypes)
+
+	serv = newTestService(ctx, t, false,
+		WithMaxProofTypes[*headertest.DummyHeader](2),
+		withProofTypes(fraudtest.DummyProofType, "Another"))
+	require.NoError(t, serv.Start(ctx))
+
+	// registered types can be registered again
+	require.NoError(t, serv.UnregisterProofType("Another"))
+	require.NoError(t, serv.RegisterProofType("Another"))
+	require.ElementsMatch(t, []fraud.ProofType{fraudtest.DummyProofType, "Another"}, serv.Topics())
+}
+
+func TestService_RegisterUnregisterProofType(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	// already registered on Start
+	require.Error(t, serv.RegisterProofType(proof.Type()))
+	// not supported by the ProofUnmarshaler
+	require.Error(t, serv.RegisterProofType("Unsupported"))
+	require.Equal(t, []fraud.ProofType{proof.Type()}, serv.Topics())
+
+	sub, err := serv.Subscribe(proof.Type())
+	require.NoError(t, err)
+	require.NoError(t, serv.AddVerifier(proof.Type(), func(fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+		return true, nil
+	}))
+
+	require.NoError(t, serv.UnregisterProofType(proof.Type()))
+	require.Empty(t, serv.Topics())
+	_, err = sub.Proof(ctx)
+	require.ErrorIs(t, err, fraud.ErrSubscriptionCancelled)
+	require.ErrorIs(t, serv.Broadcast(ctx, proof), ErrTopicNotFound)
+	require.ErrorIs(t, serv.UnregisterProofType(proof.Type()), ErrTopicNotFound)
+
+	// the verifier was removed as well, so it can be added again
+	require.NoError(t, serv.RegisterProofType(proof.Type()))
+	require.Equal(t, []fraud.ProofType{proof.Type()}, serv.Topics())
+	require.NoError(t, serv.AddVerifier(proof.Type(), func(fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+		return true, nil
+	}))
+	require.NoError(t, serv.Broadcast(ctx, proof))
+}
+
+func TestService_SubscribeJoinsLazily(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, serv.UnregisterProofType(proof.Type()))
+	require.Empty(t, serv.Topics())
+
+	// listed by the unmarshaler, but not joined
+	sub, err := serv.Subscribe(proof.Type())
+	require.NoError(t, err)
+	defer sub.Cancel()
+	require.Equal(t, []fraud.ProofType{proof.Type()}, serv.Topics())
+
+	require.NoError(t, serv.Broadcast(ctx, proof))
+	_, err = sub.Proof(ctx)
+	require.NoError(t, err)
+
+	// not listed by the unmarshaler
+	_, err = serv.Subscribe("UnknownProof")
+	require.ErrorIs(t, err, ErrTopicNotFound)
+}
+
+func TestService_AutoLeaveUnusedTopics(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false,
+		WithAutoLeaveUnusedTopics[*headertest.DummyHeader](time.Millisecond*20))
+	require.NoError(t, serv.Start(ctx))
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+
+	// a topic with a subscription is kept
+	sub, err := serv.Subscribe(proof.Type())
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond * 50)
+	require.Equal(t, []fraud.ProofType{proof.Type()}, serv.Topics())
+
+	// and left once idle without subscriptions
+	sub.Cancel()
+	require.Eventually(t, func() bool {
+		return len(serv.Topics()) == 0
+	}, time.Second, time.Millisecond*10)
+
+	// broadcasting re-joins it
+	require.NoError(t, serv.Broadcast(ctx, proof))
+	require.Equal(t, []fraud.ProofType{proof.Type()}, serv.Topics())
+	require.Eventually(t, func() bool {
+		return len(serv.Topics()) == 0
+	}, time.Second, time.Millisecond*10)
+
+	// and subscribing as well
+	sub, err = serv.Subscribe(proof.Type())
+	require.NoError(t, err)
+	defer sub.Cancel()
+	require.Equal(t, []fraud.ProofType{proof.Type()}, serv.Topics())
+	require.NoError(t, serv.Broadcast(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](2)))
+	got, err := sub.Proof(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, got.Height())
+}
+
+func TestService_WaitForProof(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false,
+		withDistinctMessages())
+	require.NoError(t, serv.Start(ctx))
+	atHeight := func(height uint64) func(fraud.Proof[*headertest.DummyHeader]) bool {
+		return func(proof fraud.Proof[*headertest.DummyHeader]) bool {
+			return proof.Height() == height
+		}
+	}
+
+	// stored
+	_, err := serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](2))
+	require.NoError(t, err)
+	proof, err := serv.WaitForProof(ctx, fraudtest.DummyProofType, atHeight(2))
+	require.NoError(t, err)
+	require.EqualValues(t, 2, proof.Height())
+
+	// live
+	waited := make(chan fraud.Proof[*headertest.DummyHeader])
+	go func() {
+		proof, err := serv.WaitForProof(ctx, fraudtest.DummyProofType, atHeight(3))
+		assert.NoError(t, err)
+		waited <- proof
+	}()
+	require.Eventually(t, func() bool {
+		return serv.hasSubscriptions(fraudtest.DummyProofType)
+	}, time.Second, time.Millisecond)
+	require.NoError(t, serv.Broadcast(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](4)))
+	require.NoError(t, serv.Broadcast(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](3)))
+	select {
+	case proof = <-waited:
+		require.EqualValues(t, 3, proof.Height())
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+
+	// nothing matches
+	ctx, cancel = context.WithTimeout(ctx, time.Millisecond*10)
+	defer cancel()
+	_, err = serv.WaitForProof(ctx, fraudtest.DummyProofType, atHeight(5))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestService_StopTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	// publishing while waiting for peers blocks closing the topic
+	broadcastCtx, broadcastCancel := context.WithCancel(ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- serv.BroadcastAndWait(broadcastCtx, fraudtest.NewValidProof[*headertest.DummyHeader](), 1)
+	}()
+	time.Sleep(time.Millisecond * 50)
+
+	stopCtx, stopCancel := context.WithTimeout(ctx, time.Millisecond*100)
+	defer stopCancel()
+	start := time.Now()
+	err := serv.Stop(stopCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+
+	broadcastCancel()
+	require.Error(t, <-errCh)
+}
+
+func TestService_StopAggregatesErrors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, withProofTypes("ProofA", "ProofB"))
+	require.NoError(t, serv.Start(ctx))
+
+	// outstanding event handlers fail closing the topics
+	for _, topic := range serv.joinedTopics() {
+		handler, err := topic.EventHandler()
+		require.NoError(t, err)
+		defer handler.Cancel()
+	}
+
+	err := serv.Stop(ctx)
+	require.ErrorContains(t, err, "ProofA")
+	require.ErrorContains(t, err, "ProofB")
+}
+
+func TestService_Clear(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, serv.Broadcast(ctx, proof))
+	_, err := serv.Get(ctx, proof.Type())
+	require.NoError(t, err)
+
+	require.NoError(t, serv.Clear(ctx, proof.Type()))
+	_, err = serv.Get(ctx, proof.Type())
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+}
+
+func TestService_ApplyVerifier(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	for height := uint64(1); height <= 3; height++ {
+		proof := fraudtest.NewValidProofAt[*headertest.DummyHeader](height)
+		require.NoError(t, serv.Broadcast(ctx, proof))
+	}
+	verifier := func(proof fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+		return proof.Height() != 2, nil
+	}
+
+	kept, dropped, err := serv.ApplyVerifier(ctx, fraudtest.DummyProofType, verifier, false)
+	require.NoError(t, err)
+	require.Equal(t, 2, kept)
+	require.Equal(t, 1, dropped)
+	proofs, err := serv.Get(ctx, fraudtest.DummyProofType)
+	require.NoError(t, err)
+	require.Len(t, proofs, 3)
+
+	kept, dropped, err = serv.ApplyVerifier(ctx, fraudtest.DummyProofType, verifier, true)
+	require.NoError(t, err)
+	require.Equal(t, 2, kept)
+	require.Equal(t, 1, dropped)
+	proofs, err = serv.Get(ctx, fraudtest.DummyProofType)
+	require.NoError(t, err)
+	require.Len(t, proofs, 2)
+	for _, proof := range proofs {
+		require.NotEqualValues(t, 2, proof.Height())
+	}
+}
+
+func TestService_StorageOnly(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	store := headertest.NewDummyStore(t)
+	serv := NewProofService[*headertest.DummyHeader](
+		nil,
+		nil,
+		func(ctx context.Context, u uint64) (*headertest.DummyHeader, error) {
+			return store.GetByHeight(ctx, u)
+		},
+		func(ctx context.Context) (*headertest.DummyHeader, error) {
+			return store.Head(ctx)
+		},
+		unmarshaler,
+		sync.MutexWrap(datastore.NewMapDatastore()),
+		false,
+		"private",
+		// there are no peers to ask without a host
+		WithRemoteKnownCheck[*headertest.DummyHeader](true),
+	)
+	require.NoError(t, serv.Start(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, serv.Stop(ctx))
+	})
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	known, err := serv.Known(ctx, proof.Type(), proof.HeaderHash())
+	require.NoError(t, err)
+	require.False(t, known)
+	_, err = serv.Subscribe(proof.Type())
+	require.ErrorIs(t, err, ErrPubSubDisabled)
+	require.ErrorIs(t, serv.Broadcast(ctx, proof), ErrPubSubDisabled)
+
+	_, err = serv.Ingest(ctx, fraudtest.NewInvalidProof[*headertest.DummyHeader]())
+
+	require.Error(t, err)
+	_, err = serv.Get(ctx, proof.Type())
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+
+	_, err = serv.Ingest(ctx, proof)
+
+	require.NoError(t, err)
+	proofs, err := serv.Get(ctx, proof.Type())
+	require.NoError(t, err)
+	require.Len(t, proofs, 1)
+}
+
+func TestService_Ingest(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+	require.NoError(t, serv.AddVerifier(fraudtest.DummyProofType,
+		func(proof fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+			return proof.Height() != 2, nil
+		}))
+
+	sub, err := serv.Subscribe(fraudtest.DummyProofType)
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	// invalid
+	_, err = serv.Ingest(ctx, fraudtest.NewInvalidProof[*headertest.DummyHeader]())
+	require.Error(t, err)
+	// rejected by the verifier
+	_, err = serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](2))
+	require.Error(t, err)
+	// above the head threshold
+	_, err = serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](100))
+	require.Error(t, err)
+	_, err = serv.Get(ctx, fraudtest.DummyProofType)
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	_, err = serv.Ingest(ctx, proof)
+	require.NoError(t, err)
+	proofs, err := serv.Get(ctx, proof.Type())
+	require.NoError(t, err)
+	require.Len(t, proofs, 1)
+
+	// ingested proofs are not broadcasted
+	shortCtx, shortCancel := context.WithTimeout(ctx, time.Millisecond*100)
+	defer shortCancel()
+	_, err = sub.Proof(shortCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestService_IngestCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	verifying, unblock := make(chan struct{}, 2), make(chan struct{})
+	t.Cleanup(func() { close(unblock) })
+	require.NoError(t, serv.AddVerifier(proof.Type(), func(fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+		verifying <- struct{}{}
+		<-unblock
+		return true, nil
+	}))
+
+	for _, run := range []func(context.Context) (ProcessStatus, error){
+		func(ctx context.Context) (ProcessStatus, error) { return serv.Ingest(ctx, proof) },
+		func(ctx context.Context) (ProcessStatus, error) { return serv.DryRun(ctx, proof) },
+	} {
+		runCtx, runCancel := context.WithCancel(ctx)
+		go func() {
+			// cancel in the middle of the verification
+			<-verifying
+			runCancel()
+		}()
+		start := time.Now()
+		status, err := run(runCtx)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, ProcessIgnored, status)
+		require.Less(t, time.Since(start), time.Second)
+	}
+
+	_, err := serv.Get(ctx, proof.Type())
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+}
+
+func newTestService(
+	ctx context.Context,
+	t *testing.T,
+	enabledSyncer bool,
+	opts ...Option[*headertest.DummyHeader],
+) *ProofService[*headertest.DummyHeader] {
+	host, ps := fraudtest.NewPubSubHost(ctx, t)
+	return newTestServiceWithPubSub(ctx, t, ps, host, enabledSyncer, opts...)
+}
+
+// unmarshaler supports DummyProof, the only ProofType of the test services by default.
+var unmarshaler = unmarshalerOf(dummyProofFactory(fraudtest.DummyProofType))
+
+// withDistinctMessages makes pubsub deliver every distinct proof published by the test services,
+// as their unsigned messages otherwise share the same default message ID and are dropped as seen.
+func withDistinctMessages() Option[*headertest.DummyHeader] {
+	return WithMessageIdFn[*headertest.DummyHeader](ContentMessageID)
+}
+
+// withProofTypes makes the test service support the given ProofTypes instead of DummyProof only.
+// Their proofs are DummyProofs, apart from UnknownProof, whose proofs are unknownProofs.
+func withProofTypes(proofTypes ...fraud.ProofType) Option[*headertest.DummyHeader] {
+	factories := make([]fraud.ProofFactory[*headertest.DummyHeader], 0, len(proofTypes))
+	for _, proofType := range proofTypes {
+		factory := dummyProofFactory(proofType)
+		if proofType == (&unknownProof{}).Type() {
+			factory.New = func() fraud.Proof[*headertest.DummyHeader] {
+				return &unknownProof{&fraudtest.DummyProof[*headertest.DummyHeader]{}}
+			}
+		}
+		factories = append(factories, factory)
+	}
+	return func(f *ProofService[*headertest.DummyHeader]) {
+		f.unmarshal = unmarshalerOf(factories...)
+	}
+}
+
+// dummyProofFactory makes DummyProofs for the given ProofType.
+func dummyProofFactory(proofType fraud.ProofType) fraud.ProofFactory[*headertest.DummyHeader] {
+	return fraud.ProofFactory[*headertest.DummyHeader]{
+		Type: proofType,
+		New: func() fraud.Proof[*headertest.DummyHeader] {
+			return &fraudtest.DummyProof[*headertest.DummyHeader]{}
+		},
+	}
+}
+
+// unmarshalerOf returns the ProofUnmarshaler of the given factories, panicking if they are not valid.
+func unmarshalerOf(
+	factories ...fraud.ProofFactory[*headertest.DummyHeader],
+) *fraud.MultiUnmarshaler[*headertest.DummyHeader] {
+	u, err := fraud.Unmarshalers[*headertest.DummyHeader](factories...)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func newTestServiceWithHost(
+	ctx context.Context,
+	t *testing.T,
+	host host.Host,
+	enabledSyncer bool,
+	opts ...Option[*headertest.DummyHeader],
+) *ProofService[*headertest.DummyHeader] {
+	ps, err := pubsub.NewFloodSub(ctx, host, pubsub.WithMessageSignaturePolicy(pubsub.StrictNoSign))
+	require.NoError(t, err)
+	return newTestServiceWithPubSub(ctx, t, ps, host, enabledSyncer, opts...)
+}
+
+func newTestServiceWithPubSub(
+	ctx context.Context,
+	t *testing.T,
+	ps *pubsub.PubSub,
+	host host.Host,
+	enabledSyncer bool,
+	opts ...Option[*headertest.DummyHeader],
+) *ProofService[*headertest.DummyHeader] {
+	store := headertest.NewDummyStore(t)
+	serv := NewProofService[*headertest.DummyHeader](
+		ps,
+		host,
+		func(ctx context.Context, u uint64) (*headertest.DummyHeader, error) {
+			return store.GetByHeight(ctx, u)
+		},
+		func(ctx context.Context) (*headertest.DummyHeader, error) {
+			return store.Head(ctx)
+		},
+		unmarshaler,
+		sync.MutexWrap(datastore.NewMapDatastore()),
+		enabledSyncer,
+		"private",
+		opts...,
+	)
+
+	t.Cleanup(func() {
+		err := serv.Stop(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	return serv
+}
+
+// withTestTracer replaces the package tracer with one recording spans in memory.
+func withTestTracer(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := tracer
+	tracer = provider.Tracer("fraudserv")
+	t.Cleanup(func() {
+		tracer = prev
+	})
+	return recorder
+}
+
+func TestService_GetterVerifier(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, withProofTypes(fraudtest.DummyProofType, "UnknownProof"))
+	require.NoError(t, serv.Start(ctx))
+
+	// DummyProof is only valid while there is no UnknownProof stored
+	err := serv.AddGetterVerifier(fraudtest.DummyProofType,
+		func(
+			ctx context.Context,
+			getter fraud.Getter[*headertest.DummyHeader],
+			_ fraud.Proof[*headertest.DummyHeader],
+		) (bool, error) {
+			_, err := getter.Get(ctx, "UnknownProof")
+			if errors.Is(err, datastore.ErrNotFound) {
+				return true, nil
+			}
+			return false, err
+		})
+	require.NoError(t, err)
+
+	status, reason := serv.DryRun(ctx, fraudtest.NewValidProof[*headertest.DummyHeader]())
+	require.Equal(t, ProcessAccepted, status, reason)
+
+	_, err = serv.Ingest(ctx, &unknownProof{fraudtest.NewValidProof[*headertest.DummyHeader]()})
+
+	require.NoError(t, err)
+	status, reason = serv.DryRun(ctx, fraudtest.NewValidProof[*headertest.DummyHeader]())
+	require.Equal(t, ProcessRejected, status)
+	require.Error(t, reason)
+}
+
+func mustMarshal(t *testing.T, proof fraud.Proof[*headertest.DummyHeader]) []byte {
+	bin, err := proof.MarshalBinary()
+	require.NoError(t, err)
+	return bin
+}
+
+type unknownProof struct {
+	*fraudtest.DummyProof[*headertest.DummyHeader]
+}
+
+func (p *unknownProof) Type() fraud.ProofType {
+	return "UnknownProof"
 }
@@ -1,7 +1,10 @@
 package fraudserv
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"sort"
@@ -17,6 +20,13 @@ import (
 
 var storePrefix = "fraud"
 
+// SHA256KeyHasher derives fixed-length datastore keys by hex encoding
+// the sha256 of the header hash.
+func SHA256KeyHasher(hash []byte) string {
+	sum := sha256.Sum256(hash)
+	return hex.EncodeToString(sum[:])
+}
+
 // put adds a Fraud Proof to the datastore with the given hash as the key.
 func put(ctx context.Context, ds datastore.Datastore, hash string, value []byte) error {
 	return ds.Put(ctx, datastore.NewKey(hash), value)
@@ -32,19 +42,71 @@ func query(ctx context.Context, ds datastore.Datastore, q q.Query) ([]q.Entry, e
 	return results.Rest()
 }
 
+// queryAfter performs the query on the given datastore for up to limit entries ordered by keys,
+// starting after the given key, or from the first one if it is empty.
+func queryAfter(ctx context.Context, ds datastore.Datastore, qry q.Query, after string, limit int) ([]q.Entry, error) {
+	qry.Orders = []q.Order{q.OrderByKey{}}
+	qry.Limit = limit
+	if after != "" {
+		filters := make([]q.Filter, 0, len(qry.Filters)+1)
+		qry.Filters = append(append(filters, qry.Filters...), q.FilterKeyCompare{Op: q.GreaterThan, Key: after})
+	}
+	return query(ctx, ds, qry)
+}
+
+// queryBatched performs the query on the given datastore in batches of the given size ordered by keys,
+// bounding the amount of entries the datastore reads at once. Every batch starts after the last key
+// of the previous one, so that entries written or deleted meanwhile do not shift the following batches.
+// Non-positive sizes disable batching.
+func queryBatched(ctx context.Context, ds datastore.Datastore, qry q.Query, batchSize int) ([]q.Entry, error) {
+	if batchSize <= 0 {
+		return query(ctx, ds, qry)
+	}
+	var entries []q.Entry
+	for {
+		var last string
+		if len(entries) > 0 {
+			last = entries[len(entries)-1].Key
+		}
+		batch, err := queryAfter(ctx, ds, qry, last, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, batch...)
+		if len(batch) < batchSize {
+			return entries, nil
+		}
+	}
+}
+
+// deleteAll deletes all the entries of the given datastore.
+func deleteAll(ctx context.Context, ds datastore.Datastore) error {
+	entries, err := query(ctx, ds, q.Query{KeysOnly: true})
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err = ds.Delete(ctx, datastore.NewKey(entry.Key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // getByHash fetches a fraud proof by its hash from local storage.
 func getByHash(ctx context.Context, ds datastore.Datastore, hash string) ([]byte, error) {
 	return ds.Get(ctx, datastore.NewKey(hash))
 }
 
-// getAll queries all Fraud Proofs by their type.
+// getAll queries all Fraud Proofs by their type with a range query, in batches of the given size if positive.
 func getAll[H header.Header[H]](
 	ctx context.Context,
 	ds datastore.Datastore,
 	proofType fraud.ProofType,
 	registry fraud.ProofUnmarshaler[H],
+	batchSize int,
 ) ([]fraud.Proof[H], error) {
-	entries, err := query(ctx, ds, q.Query{})
+	entries, err := queryBatched(ctx, ds, q.Query{}, batchSize)
 	if err != nil {
 		return nil, err
 	}
@@ -63,16 +125,53 @@ func getAll[H header.Header[H]](
 		}
 		proofs = append(proofs, proof)
 	}
+	sortProofs(proofs)
+	return proofs, nil
+}
+
+// sortProofs orders the proofs by their heights and then by their header hashes,
+// so that the order is deterministic regardless of the datastore iteration order.
+func sortProofs[H header.Header[H]](proofs []fraud.Proof[H]) {
 	sort.Slice(proofs, func(i, j int) bool {
-		return proofs[i].Height() < proofs[j].Height()
+		if proofs[i].Height() != proofs[j].Height() {
+			return proofs[i].Height() < proofs[j].Height()
+		}
+		return bytes.Compare(proofs[i].HeaderHash(), proofs[j].HeaderHash()) < 0
+	})
+}
+
+// proofsAfter returns the proofs ordered after the proof of the given height and header hash,
+// as sorted by sortProofs.
+func proofsAfter[H header.Header[H]](proofs []fraud.Proof[H], height uint64, hash []byte) []fraud.Proof[H] {
+	idx := sort.Search(len(proofs), func(i int) bool {
+		if proofs[i].Height() != height {
+			return proofs[i].Height() > height
+		}
+		return bytes.Compare(proofs[i].HeaderHash(), hash) > 0
 	})
+	return proofs[idx:]
+}
+
+// getAllRaw queries the encoded forms of all Fraud Proofs in the datastore ordered by their keys.
+func getAllRaw(ctx context.Context, ds datastore.Datastore) ([][]byte, error) {
+	entries, err := query(ctx, ds, q.Query{Orders: []q.Order{q.OrderByKey{}}})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, datastore.ErrNotFound
+	}
+	proofs := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		proofs = append(proofs, entry.Value)
+	}
 	return proofs, nil
 }
 
-func initStore(topic fraud.ProofType, ds datastore.Datastore) datastore.Datastore {
-	return namespace.Wrap(ds, makeKey(topic))
+func initStore(root string, topic fraud.ProofType, ds datastore.Datastore) datastore.Datastore {
+	return namespace.Wrap(ds, makeKey(root, topic))
 }
 
-func makeKey(topic fraud.ProofType) datastore.Key {
-	return datastore.NewKey(fmt.Sprintf("%s/%s", storePrefix, topic))
+func makeKey(root string, topic fraud.ProofType) datastore.Key {
+	return datastore.NewKey(fmt.Sprintf("%s/%s", root, topic))
 }
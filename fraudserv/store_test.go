@@ -2,16 +2,28 @@ package fraudserv
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/namespace"
+	q "github.com/ipfs/go-datastore/query"
 	ds_sync "github.com/ipfs/go-datastore/sync"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/celestiaorg/go-header/headertest"
 
+	"github.com/celestiaorg/go-fraud"
 	"github.com/celestiaorg/go-fraud/fraudtest"
 )
 
@@ -23,7 +35,7 @@ func TestStore_Put(t *testing.T) {
 	bin, err := p.MarshalBinary()
 	require.NoError(t, err)
 	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
-	store := namespace.Wrap(ds, makeKey(p.Type()))
+	store := namespace.Wrap(ds, makeKey(storePrefix, p.Type()))
 	err = put(ctx, store, string(p.HeaderHash()), bin)
 	require.NoError(t, err)
 }
@@ -36,26 +48,61 @@ func TestStore_GetAll(t *testing.T) {
 	bin, err := proof.MarshalBinary()
 	require.NoError(t, err)
 	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
-	proofStore := namespace.Wrap(ds, makeKey(proof.Type()))
+	proofStore := namespace.Wrap(ds, makeKey(storePrefix, proof.Type()))
 
 	err = put(ctx, proofStore, string(proof.HeaderHash()), bin)
 	require.NoError(t, err)
 
-	proofs, err := getAll[*headertest.DummyHeader](ctx, proofStore, proof.Type(), unmarshaler)
+	proofs, err := getAll[*headertest.DummyHeader](ctx, proofStore, proof.Type(), unmarshaler, 0)
 	require.NoError(t, err)
 	require.NotEmpty(t, proofs)
 	require.NoError(t, proof.Validate(nil))
 }
 
+func Test_sortProofs(t *testing.T) {
+	proofs := make([]fraud.Proof[*headertest.DummyHeader], 0, 6)
+	for _, height := range []uint64{2, 1} {
+		for _, hash := range []string{"c", "a", "b"} {
+			proofs = append(proofs, &hashedProof{fraudtest.NewValidProofAt[*headertest.DummyHeader](height), []byte(hash)})
+		}
+	}
+	order := func(proofs []fraud.Proof[*headertest.DummyHeader]) []string {
+		sortProofs(proofs)
+		order := make([]string, 0, len(proofs))
+		for _, proof := range proofs {
+			order = append(order, fmt.Sprintf("%d/%s", proof.Height(), proof.HeaderHash()))
+		}
+		return order
+	}
+
+	expected := []string{"1/a", "1/b", "1/c", "2/a", "2/b", "2/c"}
+	require.Equal(t, expected, order(proofs))
+	// the same proofs in any order are sorted identically
+	for i := 0; i < 10; i++ {
+		rand.Shuffle(len(proofs), func(i, j int) { proofs[i], proofs[j] = proofs[j], proofs[i] })
+		require.Equal(t, expected, order(proofs))
+	}
+}
+
+// hashedProof is a DummyProof with a custom header hash.
+type hashedProof struct {
+	*fraudtest.DummyProof[*headertest.DummyHeader]
+	hash []byte
+}
+
+func (p *hashedProof) HeaderHash() []byte {
+	return p.hash
+}
+
 func Test_GetAllFailed(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer t.Cleanup(cancel)
 
 	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
 	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
-	store := namespace.Wrap(ds, makeKey(proof.Type()))
+	store := namespace.Wrap(ds, makeKey(storePrefix, proof.Type()))
 
-	proofs, err := getAll[*headertest.DummyHeader](ctx, store, proof.Type(), unmarshaler)
+	proofs, err := getAll[*headertest.DummyHeader](ctx, store, proof.Type(), unmarshaler, 0)
 	require.Error(t, err)
 	require.ErrorIs(t, err, datastore.ErrNotFound)
 	require.Nil(t, proofs)
@@ -67,7 +114,7 @@ func Test_getByHash(t *testing.T) {
 
 	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
 	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
-	store := namespace.Wrap(ds, makeKey(proof.Type()))
+	store := namespace.Wrap(ds, makeKey(storePrefix, proof.Type()))
 	bin, err := proof.MarshalBinary()
 	require.NoError(t, err)
 	err = put(ctx, store, string(proof.HeaderHash()), bin)
@@ -75,3 +122,654 @@ func Test_getByHash(t *testing.T) {
 	_, err = getByHash(ctx, store, string(proof.HeaderHash()))
 	require.NoError(t, err)
 }
+
+func TestService_ConcurrentStoreAccess(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	bin, err := proof.MarshalBinary()
+	require.NoError(t, err)
+	hash := hex.EncodeToString(proof.HeaderHash())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, serv.put(ctx, proof.Type(), hash, bin))
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = serv.Get(ctx, proof.Type())
+		}()
+		go func() {
+			defer wg.Done()
+			_ = serv.verifyLocal(ctx, proof.Type(), hash, bin)
+		}()
+	}
+	wg.Wait()
+
+	require.True(t, serv.verifyLocal(ctx, proof.Type(), hash, bin))
+	proofs, err := serv.Get(ctx, proof.Type())
+	require.NoError(t, err)
+	require.Len(t, proofs, 1)
+}
+
+func TestService_InMemoryStore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(2)
+	require.NoError(t, err)
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	serv := newTestServiceWithHost(ctx, t, net.Hosts()[0], false, WithInMemoryStore[*headertest.DummyHeader]())
+	require.NoError(t, serv.Start(ctx))
+	require.NoError(t, serv.Broadcast(ctx, proof))
+	_, err = serv.Get(ctx, proof.Type())
+	require.NoError(t, err)
+	require.NoError(t, serv.Stop(ctx))
+
+	// a new in-memory service does not have proofs of the previous one
+	serv = newTestServiceWithHost(ctx, t, net.Hosts()[1], false, WithInMemoryStore[*headertest.DummyHeader]())
+	require.NoError(t, serv.Start(ctx))
+	_, err = serv.Get(ctx, proof.Type())
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+}
+
+func TestService_KeyHasher(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, WithKeyHasher[*headertest.DummyHeader](SHA256KeyHasher))
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	bin := mustMarshal(t, proof)
+	require.NoError(t, serv.put(ctx, proof.Type(), serv.keyHasher(proof.HeaderHash()), bin))
+
+	data, err := getByHash(ctx, serv.storeFor(proof.Type()), SHA256KeyHasher(proof.HeaderHash()))
+	require.NoError(t, err)
+	require.Equal(t, bin, data)
+	require.True(t, serv.verifyLocal(ctx, proof.Type(), serv.keyHasher(proof.HeaderHash()), bin))
+}
+
+func TestService_StoreNamespace(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, WithStoreNamespace[*headertest.DummyHeader]("/myapp/fraud"))
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, serv.Broadcast(ctx, proof))
+
+	key := datastore.NewKey("/myapp/fraud").
+		ChildString(proof.Type().String()).
+		ChildString(serv.keyHasher(proof.HeaderHash()))
+	data, err := serv.ds.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, mustMarshal(t, proof), data)
+}
+
+func TestService_StorageKey(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	for name, opts := range map[string][]Option[*headertest.DummyHeader]{
+		"default": nil,
+		"custom": {
+			WithStoreNamespace[*headertest.DummyHeader]("/myapp/fraud"),
+			WithKeyHasher[*headertest.DummyHeader](SHA256KeyHasher),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			serv := newTestService(ctx, t, false, opts...)
+			require.NoError(t, serv.Start(ctx))
+
+			proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+			require.NoError(t, serv.Broadcast(ctx, proof))
+
+			data, err := serv.ds.Get(ctx, serv.StorageKey(proof.Type(), proof.HeaderHash()))
+			require.NoError(t, err)
+			require.Equal(t, mustMarshal(t, proof), data)
+		})
+	}
+}
+
+func TestService_MaxTotalProofs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, WithMaxTotalProofs[*headertest.DummyHeader](2))
+	require.NoError(t, serv.Start(ctx))
+
+	for height := uint64(1); height <= 2; height++ {
+		status, err := serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](height))
+		require.NoError(t, err)
+		require.Equal(t, ProcessAccepted, status)
+	}
+	// stored proofs are still accepted once the store is full
+	status, err := serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](1))
+	require.NoError(t, err)
+	require.Equal(t, ProcessAccepted, status)
+
+	proof := fraudtest.NewValidProofAt[*headertest.DummyHeader](3)
+	status, err = serv.Ingest(ctx, proof)
+	require.ErrorIs(t, err, ErrStoreFull)
+	require.Equal(t, ProcessIgnored, status)
+	msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, proof)}}
+	require.Equal(t, pubsub.ValidationIgnore, serv.processIncoming(ctx, proof.Type(), "peer", msg))
+
+	proofs, err := serv.Get(ctx, proof.Type())
+	require.NoError(t, err)
+	require.Len(t, proofs, 2)
+
+	// the store is recounted once cleared
+	require.NoError(t, serv.Clear(ctx, proof.Type()))
+	status, err = serv.Ingest(ctx, proof)
+	require.NoError(t, err)
+	require.Equal(t, ProcessAccepted, status)
+}
+
+func TestService_MaxTotalProofsCountedOnStart(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, WithMaxTotalProofs[*headertest.DummyHeader](2))
+	// proofs stored before Start, including the ones of types no longer supported
+	require.NoError(t, put(ctx, initStore(storePrefix, "RemovedProof", serv.ds), "hash", []byte("{}")))
+	_, err := serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](1))
+	require.NoError(t, err)
+	require.NoError(t, serv.Start(ctx))
+
+	_, err = serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](2))
+	require.ErrorIs(t, err, ErrStoreFull)
+}
+
+func TestService_GetAllPartial(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, withProofTypes(fraudtest.DummyProofType, "CorruptedProof", "MissingProof"))
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, serv.Broadcast(ctx, proof))
+	errCorrupted := errors.New("corrupted")
+	serv.stores["CorruptedProof"] = failingQueryDatastore{
+		Datastore: datastore.NewMapDatastore(),
+		err:       errCorrupted,
+	}
+
+	all, err := serv.GetAll(ctx)
+	var getAllErr *GetAllError
+	require.ErrorAs(t, err, &getAllErr)
+	require.Len(t, getAllErr.Errors, 1)
+	require.ErrorIs(t, getAllErr.Errors["CorruptedProof"], errCorrupted)
+	require.ErrorIs(t, err, errCorrupted)
+	// the proofs of the other types are still returned
+	require.Len(t, all, 1)
+	require.Len(t, all[proof.Type()], 1)
+}
+
+func TestService_TypeQuota(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	clock := newFakeClock()
+	serv := newTestService(ctx, t, false,
+		WithTypeQuota[*headertest.DummyHeader](fraudtest.DummyProofType, 2),
+		WithClock[*headertest.DummyHeader](clock))
+	require.NoError(t, serv.Start(ctx))
+
+	// stored in the reverse order of heights, so that eviction does not depend on them
+	for height := uint64(3); height >= 1; height-- {
+		_, err := serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](height))
+		require.NoError(t, err)
+		clock.Advance(time.Second)
+	}
+	// storing a known proof again does not refresh it
+	_, err := serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](2))
+	require.NoError(t, err)
+
+	proofs, err := serv.Get(ctx, fraudtest.DummyProofType)
+	require.NoError(t, err)
+	require.Len(t, proofs, 2)
+	// the first seen is evicted, while the newest is kept
+	require.EqualValues(t, 1, proofs[0].Height())
+	require.EqualValues(t, 2, proofs[1].Height())
+
+	_, err = serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](4))
+
+	require.NoError(t, err)
+	proofs, err = serv.Get(ctx, fraudtest.DummyProofType)
+	require.NoError(t, err)
+	require.Len(t, proofs, 2)
+	require.EqualValues(t, 1, proofs[0].Height())
+	require.EqualValues(t, 4, proofs[1].Height())
+}
+
+func TestService_TypeQuotaIndexed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	clock := newFakeClock()
+	serv := newTestService(ctx, t, false,
+		WithTypeQuota[*headertest.DummyHeader](fraudtest.DummyProofType, 2),
+		WithClock[*headertest.DummyHeader](clock))
+	ds := &countingQueryDatastore{Datastore: serv.ds}
+	serv.ds = ds
+	// a proof stored before the quota was set is evicted first
+	require.NoError(t, put(ctx, serv.storeFor(fraudtest.DummyProofType), "hash", []byte("{}")))
+	require.NoError(t, serv.Start(ctx))
+
+	for height := uint64(1); height <= 5; height++ {
+		_, err := serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](height))
+		require.NoError(t, err)
+		clock.Advance(time.Second)
+	}
+	// the store is queried only once for the index of the stored proofs
+	require.EqualValues(t, 2, ds.queries.Load())
+
+	proofs, err := serv.Get(ctx, fraudtest.DummyProofType)
+	require.NoError(t, err)
+	require.Len(t, proofs, 2)
+	require.EqualValues(t, 4, proofs[0].Height())
+	require.EqualValues(t, 5, proofs[1].Height())
+
+	// the index is loaded again once the store is cleared
+	require.NoError(t, serv.Clear(ctx, fraudtest.DummyProofType))
+	_, err = serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](1))
+	require.NoError(t, err)
+	proofs, err = serv.Get(ctx, fraudtest.DummyProofType)
+	require.NoError(t, err)
+	require.Len(t, proofs, 1)
+}
+
+func TestService_ResetStore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false,
+		WithTypeQuota[*headertest.DummyHeader](fraudtest.DummyProofType, 10),
+		WithDedupWindow[*headertest.DummyHeader](time.Minute),
+		withProofTypes(fraudtest.DummyProofType, "UnknownProof"))
+	require.NoError(t, serv.Start(ctx))
+
+	_, err := serv.Ingest(ctx, fraudtest.NewValidProof[*headertest.DummyHeader]())
+
+	require.NoError(t, err)
+	_, err = serv.Ingest(ctx, &unknownProof{fraudtest.NewValidProof[*headertest.DummyHeader]()})
+	require.NoError(t, err)
+	// a proof of a type that is no longer supported
+	require.NoError(t, put(ctx, initStore(storePrefix, "RemovedProof", serv.ds), "hash", []byte("{}")))
+
+	require.NoError(t, serv.ResetStore(ctx))
+	for _, proofType := range []fraud.ProofType{fraudtest.DummyProofType, "UnknownProof", "RemovedProof"} {
+		_, err = serv.Get(ctx, proofType)
+		require.ErrorIs(t, err, datastore.ErrNotFound, proofType)
+	}
+	entries, err := query(ctx, serv.ds, q.Query{KeysOnly: true})
+	require.NoError(t, err)
+	require.Empty(t, entries)
+	require.Zero(t, serv.seen.size())
+}
+
+func TestService_PersistentWriteFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	ds := &readOnlyDatastore{Datastore: serv.ds}
+	serv.ds = ds
+	require.NoError(t, serv.Start(ctx))
+
+	for height := uint64(1); height <= persistentWriteFailures; height++ {
+		require.NoError(t, serv.Health())
+		proof := fraudtest.NewValidProofAt[*headertest.DummyHeader](height)
+		msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, proof)}}
+		// proofs are still accepted and re-gossiped
+		require.Equal(t, pubsub.ValidationAccept, serv.processIncoming(ctx, proof.Type(), "peer", msg))
+	}
+	require.ErrorIs(t, serv.Health(), ErrStoreUnwritable)
+	require.ErrorIs(t, serv.Health(), errReadOnly)
+
+	// recovers after a successful write
+	ds.writable.Store(true)
+	_, err := serv.Ingest(ctx, fraudtest.NewValidProof[*headertest.DummyHeader]())
+	require.NoError(t, err)
+	require.NoError(t, serv.Health())
+}
+
+func TestService_ReadOnlyStore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, WithReadOnlyStore[*headertest.DummyHeader](true))
+	ds := &readOnlyDatastore{Datastore: serv.ds}
+	serv.ds = ds
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	sub, err := serv.Subscribe(proof.Type())
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	require.NoError(t, serv.Broadcast(ctx, proof))
+	_, err = sub.Proof(ctx)
+	require.NoError(t, err)
+	_, err = serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](2))
+	require.NoError(t, err)
+
+	require.Zero(t, ds.puts.Load())
+	require.NoError(t, serv.Health())
+}
+
+var errReadOnly = errors.New("read-only")
+
+func TestService_Flush(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, WithSyncWrites[*headertest.DummyHeader](true))
+	ds := &syncingDatastore{Datastore: serv.ds}
+	serv.ds = ds
+	require.NoError(t, serv.Start(ctx))
+
+	require.NoError(t, serv.Flush(ctx))
+	require.Contains(t, ds.synced, datastore.NewKey(storePrefix))
+	ds.synced = nil
+
+	// every write is flushed
+	_, err := serv.Ingest(ctx, fraudtest.NewValidProof[*headertest.DummyHeader]())
+	require.NoError(t, err)
+	require.Contains(t, ds.synced, datastore.NewKey(storePrefix))
+
+	errSync := errors.New("sync failed")
+	ds.err = errSync
+	require.ErrorIs(t, serv.Flush(ctx), errSync)
+	_, err = serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](2))
+	require.ErrorIs(t, err, errSync)
+}
+
+func TestService_QueryBatchSize(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, WithQueryBatchSize[*headertest.DummyHeader](2))
+	require.NoError(t, serv.Start(ctx))
+	for height := uint64(1); height <= 5; height++ {
+		_, err := serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](height))
+		require.NoError(t, err)
+	}
+
+	proofs, err := serv.Get(ctx, fraudtest.DummyProofType)
+	require.NoError(t, err)
+	require.Len(t, proofs, 5)
+	for i, proof := range proofs {
+		require.Equal(t, uint64(i+1), proof.Height())
+	}
+}
+
+func Test_queryBatchedLive(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	ds := &hookedQueryDatastore{Datastore: ds_sync.MutexWrap(datastore.NewMapDatastore())}
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, put(ctx, ds, key, []byte(key)))
+	}
+	// the entries already read are deleted after the first batch
+	ds.afterQuery = func() {
+		ds.afterQuery = nil
+		require.NoError(t, ds.Delete(ctx, datastore.NewKey("a")))
+		require.NoError(t, ds.Delete(ctx, datastore.NewKey("b")))
+	}
+
+	entries, err := queryBatched(ctx, ds, q.Query{KeysOnly: true}, 2)
+	require.NoError(t, err)
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	require.Equal(t, []string{"/a", "/b", "/c", "/d", "/e"}, keys)
+}
+
+func TestService_SplitStore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	for _, fallback := range []bool{false, true} {
+		readDS := ds_sync.MutexWrap(datastore.NewMapDatastore())
+		writeDS := ds_sync.MutexWrap(datastore.NewMapDatastore())
+		opts := []Option[*headertest.DummyHeader]{WithSplitStore[*headertest.DummyHeader](readDS, writeDS)}
+		if fallback {
+			opts = append(opts, WithSplitStoreFallback[*headertest.DummyHeader]())
+		}
+		serv := newTestService(ctx, t, false, opts...)
+		require.NoError(t, serv.Start(ctx))
+
+		proof := fraudtest.NewValidProofAt[*headertest.DummyHeader](1)
+		_, err := serv.Ingest(ctx, proof)
+		require.NoError(t, err)
+		key := serv.StorageKey(proof.Type(), proof.HeaderHash())
+		_, err = writeDS.Get(ctx, key)
+		require.NoError(t, err)
+
+		// the replica lags behind, so the proof is only known from the write store with the fallback
+		_, err = serv.Get(ctx, proof.Type())
+		require.ErrorIs(t, err, datastore.ErrNotFound)
+		_, err = serv.GetRaw(ctx, proof.Type())
+		require.ErrorIs(t, err, datastore.ErrNotFound)
+		bin, err := proof.MarshalBinary()
+		require.NoError(t, err)
+		hash := serv.keyHasher(proof.HeaderHash())
+		require.Equal(t, fallback, serv.verifyLocal(ctx, proof.Type(), hash, bin))
+
+		// once replicated, reads are served from the replica
+		require.NoError(t, readDS.Put(ctx, key, bin))
+		proofs, err := serv.Get(ctx, proof.Type())
+		require.NoError(t, err)
+		require.Len(t, proofs, 1)
+		raw, err := serv.GetRaw(ctx, proof.Type())
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{bin}, raw)
+		require.True(t, serv.verifyLocal(ctx, proof.Type(), hash, bin))
+	}
+}
+
+// BenchmarkGetAll compares reading all the proofs with a range query to reading them key by key
+// on a datastore with a round trip latency, where fewer round trips pay off.
+func BenchmarkGetAll(b *testing.B) {
+	ctx := context.Background()
+	ds := &latencyDatastore{Datastore: ds_sync.MutexWrap(datastore.NewMapDatastore()), latency: time.Millisecond}
+	store := namespace.Wrap(ds, makeKey(storePrefix, fraudtest.DummyProofType))
+	for height := uint64(1); height <= 64; height++ {
+		proof := fraudtest.NewValidProofAt[*headertest.DummyHeader](height)
+		bin, err := proof.MarshalBinary()
+		require.NoError(b, err)
+		require.NoError(b, put(ctx, store, hex.EncodeToString(proof.HeaderHash()), bin))
+	}
+
+	b.Run("range query", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := getAll[*headertest.DummyHeader](ctx, store, fraudtest.DummyProofType, unmarshaler, 0)
+			require.NoError(b, err)
+		}
+	})
+	b.Run("range query batched", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := getAll[*headertest.DummyHeader](ctx, store, fraudtest.DummyProofType, unmarshaler, 16)
+			require.NoError(b, err)
+		}
+	})
+	b.Run("per key", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			keys, err := query(ctx, store, q.Query{KeysOnly: true})
+			require.NoError(b, err)
+			for _, key := range keys {
+				bin, err := getByHash(ctx, store, key.Key)
+				require.NoError(b, err)
+				_, err = unmarshaler.Unmarshal(fraudtest.DummyProofType, bin)
+				require.NoError(b, err)
+			}
+		}
+	})
+}
+
+// latencyDatastore delays every read by the latency, emulating a round trip to a remote backend.
+type latencyDatastore struct {
+	datastore.Datastore
+	latency time.Duration
+}
+
+func (ds *latencyDatastore) Get(ctx context.Context, key datastore.Key) ([]byte, error) {
+	time.Sleep(ds.latency)
+	return ds.Datastore.Get(ctx, key)
+}
+
+func (ds *latencyDatastore) Query(ctx context.Context, qry q.Query) (q.Results, error) {
+	time.Sleep(ds.latency)
+	return ds.Datastore.Query(ctx, qry)
+}
+
+type readOnlyDatastore struct {
+	datastore.Datastore
+	writable atomic.Bool
+	puts     atomic.Int64
+}
+
+func (ds *readOnlyDatastore) Put(ctx context.Context, key datastore.Key, value []byte) error {
+	ds.puts.Add(1)
+	if !ds.writable.Load() {
+		return errReadOnly
+	}
+	return ds.Datastore.Put(ctx, key, value)
+}
+
+type syncingDatastore struct {
+	datastore.Datastore
+	synced []datastore.Key
+	err    error
+}
+
+func (ds *syncingDatastore) Sync(ctx context.Context, prefix datastore.Key) error {
+	ds.synced = append(ds.synced, prefix)
+	if ds.err != nil {
+		return ds.err
+	}
+	return ds.Datastore.Sync(ctx, prefix)
+}
+
+// countingQueryDatastore counts the queries to the datastore.
+type countingQueryDatastore struct {
+	datastore.Datastore
+	queries atomic.Int64
+}
+
+func (ds *countingQueryDatastore) Query(ctx context.Context, qry q.Query) (q.Results, error) {
+	ds.queries.Add(1)
+	return ds.Datastore.Query(ctx, qry)
+}
+
+// hookedQueryDatastore calls afterQuery, if set, once a query is read.
+type hookedQueryDatastore struct {
+	datastore.Datastore
+	afterQuery func()
+}
+
+func (ds *hookedQueryDatastore) Query(ctx context.Context, qry q.Query) (q.Results, error) {
+	entries, err := query(ctx, ds.Datastore, qry)
+	if err != nil {
+		return nil, err
+	}
+	if ds.afterQuery != nil {
+		ds.afterQuery()
+	}
+	return q.ResultsWithEntries(qry, entries), nil
+}
+
+type failingQueryDatastore struct {
+	datastore.Datastore
+	err error
+}
+
+func (ds failingQueryDatastore) Query(context.Context, q.Query) (q.Results, error) {
+	return nil, ds.err
+}
+
+func TestService_MigrateStore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, WithTypeQuota[*headertest.DummyHeader](fraudtest.DummyProofType, 10))
+	require.NoError(t, serv.Start(ctx))
+	for height := uint64(1); height <= 3; height++ {
+		_, err := serv.Ingest(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](height))
+		require.NoError(t, err)
+	}
+	// a proof of a type no longer supported
+	require.NoError(t, serv.put(ctx, "Legacy", "legacy", []byte("legacy")))
+
+	migrated := make([]string, 0, 4)
+	err := serv.MigrateStore(ctx, func(oldKey string, value []byte) (string, []byte, bool) {
+		migrated = append(migrated, oldKey)
+		if oldKey == "/Legacy/legacy" {
+			return "", nil, false
+		}
+		proof, err := serv.unmarshal.Unmarshal(fraudtest.DummyProofType, value)
+		require.NoError(t, err)
+		if proof.Height() == 2 {
+			return "", nil, false
+		}
+		return fmt.Sprintf("/%s/height-%d", proof.Type(), proof.Height()), value, true
+	})
+	require.NoError(t, err)
+	require.Len(t, migrated, 4)
+
+	// the first-seen timestamps are migrated along with the proofs
+	for _, store := range []datastore.Datastore{
+		serv.storeFor(fraudtest.DummyProofType),
+		serv.firstSeenStore(fraudtest.DummyProofType),
+	} {
+		entries, err := query(ctx, store, q.Query{KeysOnly: true})
+		require.NoError(t, err)
+		keys := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			keys = append(keys, entry.Key)
+		}
+		require.ElementsMatch(t, []string{"/height-1", "/height-3"}, keys)
+	}
+	_, err = serv.Get(ctx, "Legacy")
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+}
+
+func TestService_MigrateKeys(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	oldKey := hex.EncodeToString(proof.HeaderHash())
+	require.NoError(t, serv.put(ctx, proof.Type(), oldKey, mustMarshal(t, proof)))
+
+	serv.keyHasher = SHA256KeyHasher
+	known, err := serv.Known(ctx, proof.Type(), proof.HeaderHash())
+	require.NoError(t, err)
+	require.False(t, known)
+
+	require.NoError(t, serv.MigrateKeys(ctx))
+
+	known, err = serv.Known(ctx, proof.Type(), proof.HeaderHash())
+	require.NoError(t, err)
+	require.True(t, known)
+	_, err = getByHash(ctx, serv.storeFor(proof.Type()), oldKey)
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+}
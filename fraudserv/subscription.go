@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sync"
 
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 
@@ -12,26 +13,248 @@ import (
 	"github.com/celestiaorg/go-fraud"
 )
 
-// subscription wraps pubsub subscription and handles Fraud Proof from the pubsub topic.
+// defaultSubscriptionBuffer matches the default buffer size of pubsub subscriptions.
+const defaultSubscriptionBuffer = 32
+
+// subscription receives Fraud Proofs of a pubsub topic from the fanout of the topic.
 type subscription[H header.Header[H]] struct {
-	subscription *pubsub.Subscription
+	// buffer keeps the received messages until they are read with Proof.
+	// Messages are dropped instead of blocking the topic once the buffer is full.
+	buffer chan *pubsub.Message
+	// done is closed on Cancel to unblock in-progress Proof calls.
+	done chan struct{}
+	// stopped is closed once the fanout stops delivering, e.g. as the ProofService is stopped.
+	stopped    <-chan struct{}
+	cancelOnce sync.Once
+	// onCancel is called once the subscription is cancelled.
+	onCancel func()
+
+	lk sync.Mutex
+	// replayed keeps the stored proofs to be delivered before the buffered ones.
+	replayed []*pubsub.Message
+	// delivered keeps the header hashes of the delivered proofs, so that a proof that is
+	// both replayed from storage and received live is delivered once.
+	// It is only set for subscriptions with replay, until the replayed proofs are drained.
+	delivered map[string]struct{}
+}
+
+func newSubscription[H header.Header[H]](bufferSize int, replay bool, onCancel func()) *subscription[H] {
+	s := &subscription[H]{
+		buffer:   make(chan *pubsub.Message, bufferSize),
+		done:     make(chan struct{}),
+		onCancel: onCancel,
+	}
+	if replay {
+		s.delivered = make(map[string]struct{})
+	}
+	return s
+}
+
+// replay queues the stored proofs for delivery ahead of the live ones.
+func (s *subscription[H]) replay(proofs []fraud.Proof[H]) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	for _, proof := range proofs {
+		if !s.markDelivered(proof) {
+			continue
+		}
+		s.replayed = append(s.replayed, &pubsub.Message{ValidatorData: proof})
+	}
+	if len(s.replayed) == 0 {
+		s.delivered = nil
+	}
+}
+
+// deliver buffers the live message unless its proof was already delivered.
+// It reports false if the buffer is full and the message is dropped.
+func (s *subscription[H]) deliver(msg *pubsub.Message) bool {
+	if proof, ok := msg.ValidatorData.(fraud.Proof[H]); ok {
+		s.lk.Lock()
+		fresh := s.markDelivered(proof)
+		s.lk.Unlock()
+		if !fresh {
+			return true
+		}
+	}
+	select {
+	case s.buffer <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// markDelivered reports whether the proof was not delivered before and marks it as delivered.
+// It must be called with the lock held.
+func (s *subscription[H]) markDelivered(proof fraud.Proof[H]) bool {
+	if s.delivered == nil {
+		return true
+	}
+	key := string(proof.HeaderHash())
+	if _, ok := s.delivered[key]; ok {
+		return false
+	}
+	s.delivered[key] = struct{}{}
+	return true
+}
+
+// nextReplayed pops the next replayed message, if any.
+// Once the replayed messages are drained, the delivered proofs are no longer tracked,
+// so that the memory of the subscription stays bounded.
+func (s *subscription[H]) nextReplayed() *pubsub.Message {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	if len(s.replayed) == 0 {
+		return nil
+	}
+	msg := s.replayed[0]
+	s.replayed = s.replayed[1:]
+	if len(s.replayed) == 0 {
+		s.replayed, s.delivered = nil, nil
+	}
+	return msg
 }
 
 func (s *subscription[H]) Proof(ctx context.Context) (fraud.Proof[H], error) {
-	if s.subscription == nil {
+	if s.buffer == nil {
 		panic("fraud: subscription is not created")
 	}
-	data, err := s.subscription.Next(ctx)
-	if err != nil {
-		return nil, err
+	select {
+	case <-s.done:
+		return nil, fraud.ErrSubscriptionCancelled
+	default:
+	}
+
+	data := s.nextReplayed()
+	if data == nil {
+		select {
+		case data = <-s.buffer:
+		case <-s.done:
+			return nil, fraud.ErrSubscriptionCancelled
+		case <-s.stopped:
+			// the proofs received before stopping are still delivered
+			select {
+			case data = <-s.buffer:
+			default:
+				return nil, ErrServiceStopped
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
 	proof, ok := data.ValidatorData.(fraud.Proof[H])
 	if !ok {
-		panic(fmt.Sprintf("fraud: unexpected type received %s", reflect.TypeOf(data.ValidatorData)))
+		err := &ErrUnexpectedValidatorData{Data: data.ValidatorData}
+		log.Errorw("received message with unexpected validator data",
+			"type", reflect.TypeOf(data.ValidatorData), "topic", data.GetTopic())
+		return nil, err
 	}
 	return proof, nil
 }
 
+// Cancel cancels the subscription. It is safe to call Cancel multiple times.
 func (s *subscription[H]) Cancel() {
-	s.subscription.Cancel()
+	s.cancelOnce.Do(func() {
+		close(s.done)
+		if s.onCancel != nil {
+			s.onCancel()
+		}
+	})
+}
+
+// fanout delivers the messages of a single pubsub subscription
+// to multiple subscriptions of the same topic.
+type fanout[H header.Header[H]] struct {
+	sub *pubsub.Subscription
+	// onDrop is called for every message that did not fit into a subscription buffer.
+	onDrop func()
+
+	// done is closed once the fanout stops delivering messages.
+	done chan struct{}
+
+	lk   sync.Mutex
+	subs map[*subscription[H]]struct{}
+}
+
+func newFanout[H header.Header[H]](sub *pubsub.Subscription, onDrop func()) *fanout[H] {
+	return &fanout[H]{
+		sub:    sub,
+		onDrop: onDrop,
+		done:   make(chan struct{}),
+		subs:   make(map[*subscription[H]]struct{}),
+	}
+}
+
+// run delivers messages until the pubsub subscription is cancelled or the context is done.
+func (fo *fanout[H]) run(ctx context.Context) {
+	defer close(fo.done)
+	for {
+		msg, err := fo.sub.Next(ctx)
+		if err != nil {
+			return
+		}
+		// skip our own republished proofs, as they were already delivered
+		if _, ok := msg.ValidatorData.(republished); ok {
+			continue
+		}
+		fo.deliver(msg)
+	}
+}
+
+// deliver delivers the message to all the subscriptions.
+func (fo *fanout[H]) deliver(msg *pubsub.Message) {
+	fo.lk.Lock()
+	defer fo.lk.Unlock()
+	for sub := range fo.subs {
+		if !sub.deliver(msg) && fo.onDrop != nil {
+			fo.onDrop()
+		}
+	}
+}
+
+func (fo *fanout[H]) add(sub *subscription[H]) {
+	fo.lk.Lock()
+	defer fo.lk.Unlock()
+	sub.stopped = fo.done
+	fo.subs[sub] = struct{}{}
+}
+
+// remove removes the subscription and reports whether there are no subscriptions left.
+func (fo *fanout[H]) remove(sub *subscription[H]) bool {
+	fo.lk.Lock()
+	defer fo.lk.Unlock()
+	delete(fo.subs, sub)
+	return len(fo.subs) == 0
+}
+
+// subscriptions returns a snapshot of the subscriptions.
+func (fo *fanout[H]) subscriptions() []*subscription[H] {
+	fo.lk.Lock()
+	defer fo.lk.Unlock()
+	subs := make([]*subscription[H], 0, len(fo.subs))
+	for sub := range fo.subs {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+// buffered returns the amount of messages buffered in the subscriptions.
+func (fo *fanout[H]) buffered() int {
+	fo.lk.Lock()
+	defer fo.lk.Unlock()
+	var buffered int
+	for sub := range fo.subs {
+		buffered += len(sub.buffer)
+	}
+	return buffered
+}
+
+// ErrUnexpectedValidatorData is returned by Subscription.Proof when a received message
+// does not carry a fraud.Proof, e.g. when another validator on the same topic sets ValidatorData.
+type ErrUnexpectedValidatorData struct {
+	Data any
+}
+
+func (e *ErrUnexpectedValidatorData) Error() string {
+	return fmt.Sprintf("fraud: unexpected type received %s", reflect.TypeOf(e.Data))
 }
@@ -0,0 +1,268 @@
+package fraudserv
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/go-header/headertest"
+
+	"github.com/celestiaorg/go-fraud"
+	"github.com/celestiaorg/go-fraud/fraudtest"
+)
+
+func TestSubscription_CancelUnblocksProof(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	sub, err := serv.Subscribe(fraudtest.DummyProofType)
+	require.NoError(t, err)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := sub.Proof(ctx)
+		errCh <- err
+	}()
+
+	// give the goroutine some time to block in Proof
+	time.Sleep(time.Millisecond * 50)
+	sub.Cancel()
+
+	select {
+	case err := <-errCh:
+		require.ErrorIs(t, err, fraud.ErrSubscriptionCancelled)
+	case <-time.After(time.Millisecond * 500):
+		t.Fatal("Proof did not return after Cancel")
+	}
+}
+
+func TestSubscription_StopUnblocksProof(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	sub, err := serv.Subscribe(fraudtest.DummyProofType)
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := sub.Proof(ctx)
+		errCh <- err
+	}()
+
+	// give the goroutine some time to block in Proof
+	time.Sleep(time.Millisecond * 50)
+	require.NoError(t, serv.Stop(ctx))
+
+	select {
+	case err := <-errCh:
+		require.ErrorIs(t, err, ErrServiceStopped)
+	case <-time.After(time.Millisecond * 500):
+		t.Fatal("Proof did not return after Stop")
+	}
+	_, err = sub.Proof(ctx)
+	require.ErrorIs(t, err, ErrServiceStopped)
+}
+
+func TestSubscription_DoubleCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	sub, err := serv.Subscribe(fraudtest.DummyProofType)
+	require.NoError(t, err)
+
+	require.NotPanics(t, func() {
+		sub.Cancel()
+		sub.Cancel()
+	})
+}
+
+func TestSubscription_ProofAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	sub, err := serv.Subscribe(fraudtest.DummyProofType)
+	require.NoError(t, err)
+	sub.Cancel()
+
+	_, err = sub.Proof(ctx)
+	require.ErrorIs(t, err, fraud.ErrSubscriptionCancelled)
+}
+
+func TestSubscription_UnexpectedValidatorData(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(1)
+	require.NoError(t, err)
+	ps, err := pubsub.NewFloodSub(ctx, net.Hosts()[0], pubsub.WithMessageSignaturePolicy(pubsub.StrictNoSign))
+	require.NoError(t, err)
+
+	const topicID = "mixed-topic"
+	// emulate a foreign validator that sets ValidatorData of another type
+	err = ps.RegisterTopicValidator(
+		topicID,
+		func(_ context.Context, _ peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+			msg.ValidatorData = "bogus"
+			return pubsub.ValidationAccept
+		},
+	)
+	require.NoError(t, err)
+
+	topic, err := ps.Join(topicID)
+	require.NoError(t, err)
+	psSub, err := topic.Subscribe()
+	require.NoError(t, err)
+	fo := newFanout[*headertest.DummyHeader](psSub, nil)
+	sub := newSubscription[*headertest.DummyHeader](defaultSubscriptionBuffer, false, nil)
+	fo.add(sub)
+	go fo.run(ctx)
+	defer psSub.Cancel()
+
+	require.NoError(t, topic.Publish(ctx, []byte("data")))
+
+	_, err = sub.Proof(ctx)
+	var errData *ErrUnexpectedValidatorData
+	require.ErrorAs(t, err, &errData)
+	require.Equal(t, "bogus", errData.Data)
+}
+
+func TestSubscription_MultipleSubscribers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	subA, err := serv.Subscribe(fraudtest.DummyProofType)
+	require.NoError(t, err)
+	defer subA.Cancel()
+	subB, err := serv.Subscribe(fraudtest.DummyProofType)
+	require.NoError(t, err)
+	defer subB.Cancel()
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, serv.Broadcast(ctx, proof))
+
+	_, err = subA.Proof(ctx)
+	require.NoError(t, err)
+	_, err = subB.Proof(ctx)
+	require.NoError(t, err)
+
+	// the remaining subscription keeps receiving after the other one is cancelled
+	subA.Cancel()
+	require.NoError(t, serv.Broadcast(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](2)))
+	_, err = subB.Proof(ctx)
+	require.NoError(t, err)
+}
+
+func TestSubscription_Replay(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false, WithSubscriptionReplay[*headertest.DummyHeader]())
+	require.NoError(t, serv.Start(ctx))
+
+	// the proof is stored before anyone subscribes
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, serv.Broadcast(ctx, proof))
+
+	sub, err := serv.Subscribe(proof.Type())
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	// emulate the live delivery of the same proof racing with the replay
+	require.True(t, sub.(*subscription[*headertest.DummyHeader]).deliver(&pubsub.Message{ValidatorData: proof}))
+
+	got, err := sub.Proof(ctx)
+	require.NoError(t, err)
+	require.Equal(t, proof.HeaderHash(), got.HeaderHash())
+	// the delivered proofs are no longer tracked once the replay is drained
+	require.Nil(t, sub.(*subscription[*headertest.DummyHeader]).delivered)
+
+	// live proofs are delivered after the replayed ones
+	live := fraudtest.NewValidProofAt[*headertest.DummyHeader](2)
+	require.NoError(t, serv.Broadcast(ctx, live))
+	got, err = sub.Proof(ctx)
+	require.NoError(t, err)
+	require.Equal(t, live.HeaderHash(), got.HeaderHash())
+
+	// and nothing is delivered twice
+	shortCtx, shortCancel := context.WithTimeout(ctx, time.Millisecond*100)
+	defer shortCancel()
+	_, err = sub.Proof(shortCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSubscription_BufferSize(t *testing.T) {
+	// more than pubsub buffers by default
+	const burst = 40
+	for _, bufferSize := range []int{0, burst} {
+		t.Run(fmt.Sprintf("buffer size %d", bufferSize), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			t.Cleanup(cancel)
+
+			opts := []Option[*headertest.DummyHeader]{
+				WithBufferedSubscriptions[*headertest.DummyHeader](burst),
+				WithoutHeadThreshold[*headertest.DummyHeader](),
+				withDistinctMessages(),
+			}
+			if bufferSize > 0 {
+				opts = append(opts, WithSubscriptionBufferSize[*headertest.DummyHeader](bufferSize))
+			}
+			serv := newTestService(ctx, t, false, opts...)
+			// the dummy store has fewer headers than the burst needs
+			serv.headerGetter = func(_ context.Context, height uint64) (*headertest.DummyHeader, error) {
+				return &headertest.DummyHeader{HeightI: height}, nil
+			}
+			require.NoError(t, serv.Start(ctx))
+
+			sub, err := serv.Subscribe(fraudtest.DummyProofType)
+			require.NoError(t, err)
+			defer sub.Cancel()
+
+			// stall the fanout, so that the proofs are left in the buffer of the pubsub subscription
+			fo := serv.subs[fraudtest.DummyProofType]
+			fo.lk.Lock()
+			for height := uint64(1); height <= burst; height++ {
+				require.NoError(t, serv.Broadcast(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](height)))
+			}
+			time.Sleep(time.Millisecond * 100)
+			fo.lk.Unlock()
+
+			var received int
+			for {
+				readCtx, cancel := context.WithTimeout(ctx, time.Millisecond*100)
+				_, err := sub.Proof(readCtx)
+				cancel()
+				if err != nil {
+					break
+				}
+				received++
+			}
+			if bufferSize == 0 {
+				require.Less(t, received, burst)
+				return
+			}
+			require.Equal(t, burst, received)
+		})
+	}
+}
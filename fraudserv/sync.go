@@ -2,6 +2,10 @@ package fraudserv
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/ipfs/go-datastore"
@@ -40,11 +44,17 @@ func (f *ProofService[H]) syncFraudProofs(ctx context.Context, id protocol.ID) {
 		proofTypes = append(proofTypes, string(proofType))
 	}
 	f.topicsLk.RUnlock()
-	connStatus := event.EvtPeerIdentificationCompleted{}
 	// peerCache is used to store discovered peers to avoid sending multiple requests to the same peer
 	peerCache := make(map[peer.ID]struct{})
+	progress := newSyncProgress(f.syncProgress)
+	var wg sync.WaitGroup
+	defer func() {
+		wg.Wait()
+		log.Infow("finished fetching fraud proofs", "fetched", progress.summary())
+	}()
 	// request proofs from `fraudRequests` many peers
-	for i := 0; i < fraudRequests; i++ {
+	for requested := 0; requested < fraudRequests; {
+		var connStatus event.EvtPeerIdentificationCompleted
 		select {
 		case <-ctx.Done():
 			return
@@ -52,66 +62,194 @@ func (f *ProofService[H]) syncFraudProofs(ctx context.Context, id protocol.ID) {
 			connStatus = e.(event.EvtPeerIdentificationCompleted)
 		}
 
-		// ignore already requested peers or ourselves as a peer
-		if _, ok := peerCache[connStatus.Peer]; ok || connStatus.Peer == f.host.ID() {
-			i--
-			continue
+		for _, pid := range f.syncCandidates(ctx, connStatus.Peer) {
+			// ignore already requested peers or ourselves as a peer
+			if _, ok := peerCache[pid]; ok || pid == f.host.ID() {
+				continue
+			}
+			peerCache[pid] = struct{}{}
+			// valid peer found, so go send proof requests
+			wg.Add(1)
+			go func(pid peer.ID) {
+				defer wg.Done()
+				f.syncFrom(ctx, id, pid, proofTypes, progress)
+			}(pid)
+			if requested++; requested == fraudRequests {
+				break
+			}
 		}
+	}
+}
 
-		peerCache[connStatus.Peer] = struct{}{}
-		// valid peer found, so go send proof requests
-		go func(pid peer.ID) {
-			ctx, span := tracer.Start(ctx, "sync_proofs")
-			defer span.End()
-
-			span.SetAttributes(
-				attribute.String("peer_id", pid.String()),
-				attribute.StringSlice("proof_types", proofTypes),
-			)
-			log.Debugw("requesting proofs from peer", "pid", pid)
-			respProofs, err := f.requestProofs(ctx, id, pid, proofTypes)
-			if err != nil {
-				log.Errorw("error while requesting fraud proofs", "err", err, "peer", pid)
-				span.RecordError(err)
-				span.SetStatus(codes.Error, err.Error())
-				return
-			}
-			if len(respProofs) == 0 {
-				log.Debugw("peer did not return any proofs", "pid", pid)
-				span.SetStatus(codes.Ok, "")
+// syncCandidates returns the peers to request proofs from once the given peer is identified.
+// It is the identified peer itself, unless a selector is set with WithSyncPeerSelector.
+func (f *ProofService[H]) syncCandidates(ctx context.Context, identified peer.ID) []peer.ID {
+	if f.syncPeerSelector == nil {
+		return []peer.ID{identified}
+	}
+	return f.syncPeerSelector(ctx)
+}
+
+// syncFrom requests proofs of the given types from the peer and publishes them
+// to all local subscriptions for verification.
+func (f *ProofService[H]) syncFrom(
+	ctx context.Context,
+	id protocol.ID,
+	pid peer.ID,
+	proofTypes []string,
+	progress *syncProgress,
+) {
+	ctx, span := f.tracer.Start(ctx, "sync_proofs")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("peer_id", pid.String()),
+		attribute.StringSlice("proof_types", proofTypes),
+	)
+	log.Debugw("requesting proofs from peer", "pid", pid)
+	f.syncMetrics.observeAttempt(ctx)
+	respProofs, err := f.requestProofs(ctx, id, pid, proofTypes)
+	fetched := make(map[fraud.ProofType]int, len(respProofs))
+	for _, data := range respProofs {
+		fetched[fraud.ProofType(data.Type)] += len(data.Value)
+	}
+	f.syncMetrics.observeResult(ctx, fetched, err)
+	if err != nil {
+		log.Errorw("error while requesting fraud proofs", "err", err, "peer", pid)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	if len(respProofs) == 0 {
+		log.Debugw("peer did not return any proofs", "pid", pid)
+		span.SetStatus(codes.Ok, "")
+		return
+	}
+	log.Debugw("got fraud proofs from peer", "pid", pid)
+	for _, data := range respProofs {
+		progress.add(fraud.ProofType(data.Type), len(data.Value))
+		f.topicsLk.RLock()
+		topic, ok := f.topics[fraud.ProofType(data.Type)]
+		f.topicsLk.RUnlock()
+		if !ok {
+			log.Errorf("topic for %s does not exist", fraud.ProofType(data.Type))
+			continue
+		}
+		for _, val := range data.Value {
+			if ctx.Err() != nil {
+				span.RecordError(ctx.Err())
 				return
 			}
-			log.Debugw("got fraud proofs from peer", "pid", pid)
-			for _, data := range respProofs {
-				f.topicsLk.RLock()
-				topic, ok := f.topics[fraud.ProofType(data.Type)]
-				f.topicsLk.RUnlock()
-				if !ok {
-					log.Errorf("topic for %s does not exist", fraud.ProofType(data.Type))
-					continue
-				}
-				for _, val := range data.Value {
-					err = topic.Publish(
-						ctx,
-						val,
-						// broadcast across all local subscriptions in order to verify fraud proof and to stop services
-						pubsub.WithLocalPublication(true),
-						// key can be nil because it will not be verified in this case
-						pubsub.WithSecretKeyAndPeerId(nil, pid),
-					)
-					if err != nil {
-						log.Error(err)
-						span.RecordError(err)
-					}
-				}
+			if err = f.publishSynced(ctx, topic, val, pid); err != nil {
+				log.Error(err)
+				span.RecordError(err)
 			}
-			span.SetStatus(codes.Ok, "")
-		}(connStatus.Peer)
+		}
+	}
+	span.SetStatus(codes.Ok, "")
+}
+
+// publishSynced publishes the proof fetched from the peer across all local subscriptions
+// in order to verify it and to stop services.
+func (f *ProofService[H]) publishSynced(ctx context.Context, topic *pubsub.Topic, val []byte, pid peer.ID) error {
+	done := f.markSyncing(val)
+	defer done()
+	return topic.Publish(
+		ctx,
+		val,
+		pubsub.WithLocalPublication(true),
+		// key can be nil because it will not be verified in this case
+		pubsub.WithSecretKeyAndPeerId(nil, pid),
+	)
+}
+
+// markSyncing marks the proof as being published locally by the sync until done is called.
+// Local publications are validated synchronously, so the mark is visible to the validation.
+func (f *ProofService[H]) markSyncing(data []byte) (done func()) {
+	key := sha256.Sum256(data)
+	f.syncingLk.Lock()
+	f.syncing[key]++
+	f.syncingLk.Unlock()
+	return func() {
+		f.syncingLk.Lock()
+		defer f.syncingLk.Unlock()
+		if f.syncing[key]--; f.syncing[key] == 0 {
+			delete(f.syncing, key)
+		}
+	}
+}
+
+// isSyncing reports whether the proof is being published locally by the sync.
+func (f *ProofService[H]) isSyncing(data []byte) bool {
+	f.syncingLk.Lock()
+	defer f.syncingLk.Unlock()
+	return f.syncing[sha256.Sum256(data)] > 0
+}
+
+// syncProgress counts proofs fetched from peers during sync.
+type syncProgress struct {
+	lk      sync.Mutex
+	fetched map[fraud.ProofType]int
+	report  func(fraud.ProofType, int)
+}
+
+func newSyncProgress(report func(fraud.ProofType, int)) *syncProgress {
+	return &syncProgress{
+		fetched: make(map[fraud.ProofType]int),
+		report:  report,
+	}
+}
+
+// add counts the proofs fetched from a peer and reports the total fetched for the ProofType.
+func (p *syncProgress) add(proofType fraud.ProofType, amount int) {
+	p.lk.Lock()
+	p.fetched[proofType] += amount
+	total := p.fetched[proofType]
+	p.lk.Unlock()
+	if p.report != nil {
+		p.report(proofType, total)
+	}
+}
+
+// summary returns the total amount of fetched proofs per ProofType.
+func (p *syncProgress) summary() map[fraud.ProofType]int {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	summary := make(map[fraud.ProofType]int, len(p.fetched))
+	for proofType, fetched := range p.fetched {
+		summary[proofType] = fetched
 	}
+	return summary
 }
 
-// handleFraudMessageRequest handles an incoming FraudMessageRequest.
+// handleFraudMessageRequest handles an incoming FraudMessageRequest,
+// processing the proofs pushed by the peer, if any.
+// Requests of peers not pushing proofs are reset if serving is disabled.
 func (f *ProofService[H]) handleFraudMessageRequest(stream network.Stream) {
+	req, ok := readRequest(stream)
+	if !ok {
+		return
+	}
+	if len(req.Pushed) > 0 {
+		if err := f.handlePushed(stream.Conn().RemotePeer(), req.Pushed); err != nil {
+			stream.Reset() //nolint:errcheck
+			log.Debugw("refusing pushed proofs", "err", err, "peer", stream.Conn().RemotePeer())
+			return
+		}
+	} else if f.serveDisabled {
+		stream.Reset() //nolint:errcheck
+		return
+	}
+	if f.serveDisabled {
+		req.RequestedProofType = nil
+	}
+	f.respond(stream, req, func(proof fraud.Proof[H]) ([]byte, error) {
+		return proof.MarshalBinary()
+	})
+}
+
+// readRequest reads the FraudMessageRequest from the stream, resetting the stream on failure.
+func readRequest(stream network.Stream) (*pb.FraudMessageRequest, bool) {
 	req := &pb.FraudMessageRequest{}
 	if err := stream.SetReadDeadline(time.Now().Add(readDeadline)); err != nil {
 		log.Warn(err)
@@ -120,33 +258,80 @@ func (f *ProofService[H]) handleFraudMessageRequest(stream network.Stream) {
 	if err != nil {
 		stream.Reset() //nolint:errcheck
 		log.Errorw("handling fraud message request failed", "err", err)
-		return
+		return nil, false
 	}
 	if err = stream.CloseRead(); err != nil {
 		log.Warn(err)
 	}
+	return req, true
+}
 
+// respond responds to the FraudMessageRequest with the stored proofs
+// of the requested types encoded with the given encode func. The proofs of a type are narrowed
+// to the ones of the header hashes requested for it, if any.
+// A response capped by serveMaxBytes carries the Cursor to continue it from, as the type
+// and the inventory item of the last served proof, and is continued by requests echoing the Cursor.
+func (f *ProofService[H]) respond(
+	stream network.Stream,
+	req *pb.FraudMessageRequest,
+	encode func(fraud.Proof[H]) ([]byte, error),
+) {
+	var err error
 	resp := &pb.FraudMessageResponse{}
 	resp.Proofs = make([]*pb.ProofResponse, 0, len(req.RequestedProofType))
+	hashes := make(map[string][][]byte, len(req.RequestedHashes))
+	for _, requested := range req.RequestedHashes {
+		hashes[requested.Type] = append(hashes[requested.Type], requested.Value...)
+	}
+	proofTypes, after, err := continued(req)
+	if err != nil {
+		stream.Reset() //nolint:errcheck
+		log.Debugw("refusing to continue the response", "err", err, "peer", stream.Conn().RemotePeer())
+		return
+	}
+	// size of the served proofs, capped with serveMaxBytes
+	size, capped := 0, false
 	// retrieve fraud proofs as provided by the FraudMessageRequest proofTypes.
-	for _, p := range req.RequestedProofType {
-		proofs, err := f.Get(f.ctx, fraud.ProofType(p))
+	for i, p := range proofTypes {
+		proofs, err := f.requested(f.ctx, fraud.ProofType(p), hashes[p])
 		if err != nil {
 			if err != datastore.ErrNotFound {
 				log.Error(err)
 			}
 			continue
 		}
+		if i == 0 && after != nil {
+			proofs = proofsAfter(proofs, after.height, after.hash)
+		}
 		pbProofs := &pb.ProofResponse{Type: p, Value: make([][]byte, 0, len(proofs))}
+		var last fraud.Proof[H]
 		for _, proof := range proofs {
-			bin, err := proof.MarshalBinary()
+			bin, err := encode(proof)
 			if err != nil {
 				log.Error(err)
 				continue
 			}
+			// at least one proof is served, so that the response always advances the Cursor
+			if f.serveMaxBytes > 0 && size > 0 && size+len(bin) > f.serveMaxBytes {
+				capped = true
+				resp.Cursor = &pb.ProofResponse{Type: p}
+				if last != nil {
+					resp.Cursor.Value = [][]byte{inventoryItem(last.Height(), last.HeaderHash())}
+				}
+				break
+			}
+			size += len(bin)
 			pbProofs.Value = append(pbProofs.Value, bin)
+			last = proof
+		}
+		if len(pbProofs.Value) > 0 || !capped {
+			resp.Proofs = append(resp.Proofs, pbProofs)
+		}
+		if capped {
+			log.Debugw("capped the response to the max bytes", "peer", stream.Conn().RemotePeer(),
+				"maxBytes", f.serveMaxBytes)
+			break
 		}
-		resp.Proofs = append(resp.Proofs, pbProofs)
 	}
 
 	if err = stream.SetWriteDeadline(time.Now().Add(writeDeadline)); err != nil {
@@ -162,3 +347,72 @@ func (f *ProofService[H]) handleFraudMessageRequest(stream network.Stream) {
 		log.Errorw("error while closing a writer in stream", "err", err)
 	}
 }
+
+// cursor is the position of the last proof served of a type in a capped response.
+type cursor struct {
+	height uint64
+	hash   []byte
+}
+
+// continued returns the requested proof types left to respond with for the FraudMessageRequest
+// and the position of the last proof served of the first of them, if the request continues
+// a capped response with its Cursor. It errors on Cursors not matching the request.
+func continued(req *pb.FraudMessageRequest) (proofTypes []string, after *cursor, err error) {
+	if req.Cursor == nil {
+		return req.RequestedProofType, nil, nil
+	}
+	for i, p := range req.RequestedProofType {
+		if p == req.Cursor.Type {
+			proofTypes = req.RequestedProofType[i:]
+			break
+		}
+	}
+	if proofTypes == nil {
+		return nil, nil, fmt.Errorf("fraud: cursor of unrequested proof type %s", req.Cursor.Type)
+	}
+	switch len(req.Cursor.Value) {
+	case 0:
+		return proofTypes, nil, nil
+	case 1:
+		height, hash, err := parseInventoryItem(req.Cursor.Value[0])
+		if err != nil {
+			return nil, nil, fmt.Errorf("fraud: malformed cursor: %w", err)
+		}
+		return proofTypes, &cursor{height: height, hash: hash}, nil
+	default:
+		return nil, nil, errors.New("fraud: malformed cursor: more than one position")
+	}
+}
+
+// requested returns the stored proofs of the ProofType ordered by their heights, narrowed to the ones
+// of the given header hashes, if any. It returns datastore.ErrNotFound if there are none.
+func (f *ProofService[H]) requested(
+	ctx context.Context,
+	proofType fraud.ProofType,
+	hashes [][]byte,
+) ([]fraud.Proof[H], error) {
+	if len(hashes) == 0 {
+		return f.Get(ctx, proofType)
+	}
+	store := f.readStoreFor(proofType)
+	proofs := make([]fraud.Proof[H], 0, len(hashes))
+	for _, hash := range hashes {
+		bin, err := getByHash(ctx, store, f.keyHasher(hash))
+		switch {
+		case errors.Is(err, datastore.ErrNotFound):
+			continue
+		case err != nil:
+			return nil, err
+		}
+		proof, err := f.unmarshal.Unmarshal(proofType, bin)
+		if err != nil {
+			return nil, err
+		}
+		proofs = append(proofs, proof)
+	}
+	if len(proofs) == 0 {
+		return nil, datastore.ErrNotFound
+	}
+	sortProofs(proofs)
+	return proofs, nil
+}
@@ -0,0 +1,216 @@
+package fraudserv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/celestiaorg/go-header"
+
+	"github.com/celestiaorg/go-fraud"
+)
+
+// ProcessReason is the reason of the decision on a Proof other than a plain acceptance.
+type ProcessReason string
+
+// Reasons of not accepting a Proof reported by the validation pipeline.
+const (
+	ReasonHeadUnavailable   ProcessReason = "head_unavailable"
+	ReasonExpired           ProcessReason = "expired"
+	ReasonAboveThreshold    ProcessReason = "above_threshold"
+	ReasonHeaderUnavailable ProcessReason = "header_unavailable"
+	ReasonHeightMismatch    ProcessReason = "header_height_mismatch"
+	ReasonVerifierFailed    ProcessReason = "verifier_failed"
+	ReasonVerifierRejected  ProcessReason = "verifier_rejected"
+	ReasonInvalidProof      ProcessReason = "invalid_proof"
+)
+
+// Reasons of the decisions on incoming proofs made before the validation pipeline.
+const (
+	ReasonUnmarshalFailed ProcessReason = "unmarshal_failed"
+	ReasonNonCanonical    ProcessReason = "non_canonical"
+	ReasonDuplicate       ProcessReason = "duplicate"
+	ReasonKnown           ProcessReason = "known"
+	ReasonRepublished     ProcessReason = "republished"
+	ReasonRaw             ProcessReason = "raw"
+	ReasonStoreFull       ProcessReason = "store_full"
+)
+
+// ReasonRejectedPush is the reason of blacklisting the peers pushing proofs rejected by the ProofService.
+const ReasonRejectedPush ProcessReason = "rejected_push"
+
+// DefaultResultPolicy maps the reason of not accepting a Proof to the pubsub ValidationResult
+// the ProofService uses by default: transient failures and proofs already seen are ignored,
+// while invalid proofs are rejected. It is meant for the policies set with WithResultPolicy
+// to fall back to.
+func DefaultResultPolicy(reason ProcessReason) pubsub.ValidationResult {
+	switch reason {
+	case ReasonHeadUnavailable, ReasonHeaderUnavailable, ReasonDuplicate, ReasonKnown, ReasonStoreFull:
+		return pubsub.ValidationIgnore
+	default:
+		return pubsub.ValidationReject
+	}
+}
+
+// ProcessStatus is the outcome of processing a Proof.
+type ProcessStatus int
+
+const (
+	// ProcessUnknown is the zero ProcessStatus, never reported for a processed Proof,
+	// so that an unset status is not taken for an accepted one.
+	ProcessUnknown ProcessStatus = iota
+	// ProcessAccepted means the Proof is valid.
+	ProcessAccepted
+	// ProcessIgnored means the Proof could not be validated for a transient reason,
+	// e.g. an unavailable header, and may be accepted later. It is not the fault of its sender.
+	ProcessIgnored
+	// ProcessRejected means the Proof is invalid and its sender may be punished.
+	ProcessRejected
+)
+
+// String returns the name of the ProcessStatus.
+func (s ProcessStatus) String() string {
+	switch s {
+	case ProcessUnknown:
+		return "unknown"
+	case ProcessAccepted:
+		return "accepted"
+	case ProcessIgnored:
+		return "ignored"
+	case ProcessRejected:
+		return "rejected"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// processStatus maps the pubsub ValidationResult to the ProcessStatus.
+func processStatus(res pubsub.ValidationResult) ProcessStatus {
+	switch res {
+	case pubsub.ValidationAccept:
+		return ProcessAccepted
+	case pubsub.ValidationIgnore:
+		return ProcessIgnored
+	default:
+		return ProcessRejected
+	}
+}
+
+// shortHashLen is the amount of header hash bytes identifying a Proof in logs.
+const shortHashLen = 4
+
+// resultString returns the name of the pubsub ValidationResult for logs.
+func resultString(res pubsub.ValidationResult) string {
+	switch res {
+	case pubsub.ValidationAccept:
+		return "accept"
+	case pubsub.ValidationReject:
+		return "reject"
+	case pubsub.ValidationIgnore:
+		return "ignore"
+	default:
+		return fmt.Sprintf("unknown(%d)", res)
+	}
+}
+
+// checkExpiry fails with ErrProofExpired if the Proof is fraud.Expiring and expired
+// at the current network head, so that it isn't published only to be dropped by peers.
+func (f *ProofService[H]) checkExpiry(ctx context.Context, proof fraud.Proof[H]) error {
+	expiring, ok := proof.(fraud.Expiring)
+	if !ok {
+		return nil
+	}
+	head, err := f.headGetter(ctx)
+	if err != nil {
+		return fmt.Errorf("fraud: fetching network head: %w", err)
+	}
+	if expiring.Expiry() < head.Height() {
+		return fmt.Errorf("%w at height %d, network head is at %d", ErrProofExpired, expiring.Expiry(), head.Height())
+	}
+	return nil
+}
+
+// checkHeight rejects the Proof above the max allowed height.
+func checkHeight[H header.Header[H]](
+	proof fraud.Proof[H],
+	maxHeight uint64,
+) (pubsub.ValidationResult, ProcessReason, error) {
+	if proof.Height() > maxHeight {
+		return pubsub.ValidationReject, ReasonAboveThreshold,
+			fmt.Errorf("received proof above the max threshold."+
+				"maxHeight: %d, proofHeight: %d, proofType: %s",
+				maxHeight,
+				proof.Height(),
+				proof.Type(),
+			)
+	}
+	return pubsub.ValidationAccept, "", nil
+}
+
+// fetchHeader fetches the extended header the Proof is verified against,
+// ignoring the Proof if it is unavailable.
+func fetchHeader[H header.Header[H]](
+	ctx context.Context,
+	proof fraud.Proof[H],
+	fetch fraud.HeaderFetcher[H],
+) (H, pubsub.ValidationResult, ProcessReason, error) {
+	fetchCtx, span := startSpan(ctx, "fetch_header")
+	extHeader, err := fetch(fetchCtx, proof.Height())
+	span.End()
+	if err != nil {
+		return extHeader, pubsub.ValidationIgnore, ReasonHeaderUnavailable, fmt.Errorf("fetching header: %w", err)
+	}
+	if extHeader.IsZero() {
+		return extHeader, pubsub.ValidationIgnore, ReasonHeaderUnavailable, errors.New("fetched empty header")
+	}
+	if extHeader.Height() != proof.Height() {
+		return extHeader, pubsub.ValidationReject, ReasonHeightMismatch,
+			fmt.Errorf("fetched header at height %d instead of %d", extHeader.Height(), proof.Height())
+	}
+	return extHeader, pubsub.ValidationAccept, "", nil
+}
+
+// runVerifier executes the verifier for the type of the Proof, if exists.
+func runVerifier[H header.Header[H]](
+	ctx context.Context,
+	proof fraud.Proof[H],
+	verifier fraud.Verifier[H],
+) (pubsub.ValidationResult, ProcessReason, error) {
+	if verifier == nil {
+		return pubsub.ValidationAccept, "", nil
+	}
+	_, span := startSpan(ctx, "run_verifier")
+	status, err := verifier(proof)
+	span.End()
+	if err != nil {
+		return pubsub.ValidationReject, ReasonVerifierFailed, fmt.Errorf("running the verifier: %w", err)
+	}
+	if !status {
+		return pubsub.ValidationReject, ReasonVerifierRejected, errors.New("rejected by the verifier")
+	}
+	return pubsub.ValidationAccept, "", nil
+}
+
+// validateProof validates the Proof against its extended header.
+func validateProof[H header.Header[H]](
+	ctx context.Context,
+	proof fraud.Proof[H],
+	extHeader H,
+) (pubsub.ValidationResult, ProcessReason, error) {
+	_, span := startSpan(ctx, "validate")
+	err := proof.Validate(extHeader)
+	span.End()
+	if err != nil {
+		return pubsub.ValidationReject, ReasonInvalidProof, err
+	}
+	return pubsub.ValidationAccept, "", nil
+}
+
+// startSpan starts a child span of the span in the context with a tracer of the same TracerProvider,
+// so that sub-steps are recorded along with their step, e.g. with WithTracerProvider.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return trace.SpanFromContext(ctx).TracerProvider().Tracer(tracerName).Start(ctx, name)
+}
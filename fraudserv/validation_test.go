@@ -0,0 +1,681 @@
+package fraudserv
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	logging "github.com/ipfs/go-log/v2"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/go-header/headertest"
+
+	"github.com/celestiaorg/go-fraud"
+	"github.com/celestiaorg/go-fraud/fraudtest"
+)
+
+func TestService_validate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	store := headertest.NewDummyStore(t)
+	fetchHeader := func(ctx context.Context, height uint64) (*headertest.DummyHeader, error) {
+		return store.GetByHeight(ctx, height)
+	}
+	accept := func(fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+		return true, nil
+	}
+
+	tests := []struct {
+		name        string
+		proof       fraud.Proof[*headertest.DummyHeader]
+		fetchHeader fraud.HeaderFetcher[*headertest.DummyHeader]
+		verifier    fraud.Verifier[*headertest.DummyHeader]
+		result      pubsub.ValidationResult
+		reason      ProcessReason
+	}{
+		{
+			name:        "valid",
+			proof:       fraudtest.NewValidProof[*headertest.DummyHeader](),
+			fetchHeader: fetchHeader,
+			verifier:    accept,
+			result:      pubsub.ValidationAccept,
+		},
+		{
+			name:        "above threshold",
+			proof:       fraudtest.NewValidProofAt[*headertest.DummyHeader](11),
+			fetchHeader: fetchHeader,
+			result:      pubsub.ValidationReject,
+			reason:      ReasonAboveThreshold,
+		},
+		{
+			name:  "header unavailable",
+			proof: fraudtest.NewValidProof[*headertest.DummyHeader](),
+			fetchHeader: func(context.Context, uint64) (*headertest.DummyHeader, error) {
+				return nil, errors.New("not found")
+			},
+			result: pubsub.ValidationIgnore,
+			reason: ReasonHeaderUnavailable,
+		},
+		{
+			name:  "header height mismatch",
+			proof: fraudtest.NewValidProof[*headertest.DummyHeader](),
+			fetchHeader: func(ctx context.Context, _ uint64) (*headertest.DummyHeader, error) {
+				return store.Head(ctx)
+			},
+			result: pubsub.ValidationReject,
+			reason: ReasonHeightMismatch,
+		},
+		{
+			name:        "verifier failed",
+			proof:       fraudtest.NewValidProof[*headertest.DummyHeader](),
+			fetchHeader: fetchHeader,
+			verifier: func(fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+				return false, errors.New("failed")
+			},
+			result: pubsub.ValidationReject,
+			reason: ReasonVerifierFailed,
+		},
+		{
+			name:        "verifier rejected",
+			proof:       fraudtest.NewValidProof[*headertest.DummyHeader](),
+			fetchHeader: fetchHeader,
+			verifier: func(fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+				return false, nil
+			},
+			result: pubsub.ValidationReject,
+			reason: ReasonVerifierRejected,
+		},
+		{
+			name:        "invalid",
+			proof:       fraudtest.NewInvalidProof[*headertest.DummyHeader](),
+			fetchHeader: fetchHeader,
+			verifier:    accept,
+			result:      pubsub.ValidationReject,
+			reason:      ReasonInvalidProof,
+		},
+		{
+			name:        "verifier before validation",
+			proof:       fraudtest.NewInvalidProof[*headertest.DummyHeader](),
+			fetchHeader: fetchHeader,
+			verifier: func(fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+				return false, nil
+			},
+			result: pubsub.ValidationReject,
+			reason: ReasonVerifierRejected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the head is at 10
+			serv := newTestService(ctx, t, false,
+				WithHeadThresholdFor[*headertest.DummyHeader](fraudtest.DummyProofType, 0),
+				WithHeaderFetchers[*headertest.DummyHeader](tt.fetchHeader))
+			if tt.verifier != nil {
+				require.NoError(t, serv.AddVerifier(fraudtest.DummyProofType, tt.verifier))
+			}
+			require.NoError(t, serv.Start(ctx))
+
+			result, reason, err := serv.validate(ctx, tt.proof)
+			require.Equal(t, tt.result, result)
+			require.Equal(t, tt.reason, reason)
+			if tt.result == pubsub.ValidationAccept {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				require.Equal(t, tt.result, DefaultResultPolicy(tt.reason))
+			}
+		})
+	}
+}
+
+func TestService_HeadThresholdFor(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false,
+		WithHeadThresholdFor[*headertest.DummyHeader](fraudtest.DummyProofType, 0),
+		WithHeadThresholdFor[*headertest.DummyHeader]("UnknownProof", 100))
+	require.NoError(t, serv.Start(ctx))
+
+	// the head is at 10
+	_, reason, err := serv.validate(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](11))
+	require.Error(t, err)
+	require.Equal(t, ReasonAboveThreshold, reason)
+	_, reason, err = serv.validate(ctx, fraudtest.NewValidProofAt[*headertest.DummyHeader](10))
+	require.NoError(t, err)
+	require.Empty(t, reason)
+
+	// passes the threshold, but there is no header for it yet
+	proof := &unknownProof{fraudtest.NewValidProofAt[*headertest.DummyHeader](110)}
+	_, reason, err = serv.validate(ctx, proof)
+	require.Error(t, err)
+	require.Equal(t, ReasonHeaderUnavailable, reason)
+	proof = &unknownProof{fraudtest.NewValidProofAt[*headertest.DummyHeader](111)}
+	_, reason, err = serv.validate(ctx, proof)
+	require.Error(t, err)
+	require.Equal(t, ReasonAboveThreshold, reason)
+}
+
+func TestService_HeaderHeightMismatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	store := headertest.NewDummyStore(t)
+	// a buggy fetcher returning the head regardless of the requested height
+	buggy := func(ctx context.Context, _ uint64) (*headertest.DummyHeader, error) {
+		return store.Head(ctx)
+	}
+	serv := newTestService(ctx, t, false, WithHeaderFetchers[*headertest.DummyHeader](buggy))
+	require.NoError(t, serv.Start(ctx))
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, proof)}}
+	res := serv.processIncoming(ctx, proof.Type(), "peer", msg)
+	require.Equal(t, pubsub.ValidationReject, res)
+	_, err := serv.Ingest(ctx, proof)
+	require.Error(t, err)
+
+	_, err = serv.Get(ctx, proof.Type())
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+}
+
+func TestService_DryRun(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+	require.NoError(t, serv.AddVerifier(fraudtest.DummyProofType,
+		func(proof fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+			return proof.Height() != 2, nil
+		}))
+
+	proofs := []fraud.Proof[*headertest.DummyHeader]{
+		fraudtest.NewInvalidProof[*headertest.DummyHeader](),
+		fraudtest.NewValidProofAt[*headertest.DummyHeader](2),   // rejected by the verifier
+		fraudtest.NewValidProofAt[*headertest.DummyHeader](100), // above the head threshold
+		fraudtest.NewPanickingProof[*headertest.DummyHeader](),  // rejected instead of panicking
+		fraudtest.NewValidProof[*headertest.DummyHeader](),
+	}
+	for _, proof := range proofs {
+		status, reason := serv.DryRun(ctx, proof)
+		require.Equal(t, status == ProcessAccepted, reason == nil)
+		if proof.(*fraudtest.DummyProof[*headertest.DummyHeader]).Panics {
+			require.Equal(t, ProcessRejected, status)
+		}
+		// nothing is stored on dry run
+		known, err := serv.Known(ctx, proof.Type(), proof.HeaderHash())
+		require.NoError(t, err)
+		require.False(t, known)
+
+		ingested, err := serv.Ingest(ctx, proof)
+		require.Equal(t, status, ingested, "proof at height %d", proof.Height())
+		require.Equal(t, status == ProcessAccepted, err == nil, "proof at height %d", proof.Height())
+	}
+}
+
+func TestService_DecisionLogFields(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	require.NoError(t, logging.SetLogLevel("fraudserv", "debug"))
+	t.Cleanup(func() {
+		logging.SetAllLoggers(logging.GetConfig().Level)
+	})
+	reader := logging.NewPipeReader(logging.PipeFormat(logging.JSONOutput))
+	entries := make(chan map[string]interface{}, 16)
+	go func() {
+		defer close(entries)
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			entry := make(map[string]interface{})
+			if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry["logger"] == "fraudserv" {
+				entries <- entry
+			}
+		}
+	}()
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	valid := fraudtest.NewValidProofAt[*headertest.DummyHeader](1)
+	invalid := &fraudtest.DummyProof[*headertest.DummyHeader]{ProofHeight: 2}
+	msg := func(proof fraud.Proof[*headertest.DummyHeader]) *pubsub.Message {
+		return &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, proof)}}
+	}
+	require.Equal(t, pubsub.ValidationAccept, serv.processIncoming(ctx, valid.Type(), "peer", msg(valid)))
+	require.Equal(t, pubsub.ValidationIgnore, serv.processIncoming(ctx, valid.Type(), "peer", msg(valid)))
+	require.Equal(t, pubsub.ValidationReject, serv.processIncoming(ctx, invalid.Type(), "peer", msg(invalid)))
+	require.NoError(t, reader.Close())
+
+	expected := []struct {
+		proof  fraud.Proof[*headertest.DummyHeader]
+		result string
+	}{
+		{valid, "accept"},
+		{valid, "ignore"},
+		{invalid, "reject"},
+	}
+	var decisions []map[string]interface{}
+	for entry := range entries {
+		if _, ok := entry["result"]; ok {
+			decisions = append(decisions, entry)
+		}
+	}
+	require.Len(t, decisions, len(expected))
+	for i, exp := range expected {
+		require.Equal(t, "private", decisions[i]["networkID"])
+		require.Equal(t, hex.EncodeToString(exp.proof.HeaderHash()[:shortHashLen]), decisions[i]["hash"])
+		require.Equal(t, exp.result, decisions[i]["result"])
+	}
+}
+
+type expiringProof struct {
+	*fraudtest.DummyProof[*headertest.DummyHeader]
+	expiry uint64
+}
+
+func (p *expiringProof) Expiry() uint64 {
+	return p.expiry
+}
+
+func TestService_ExpiredProof(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	// the head of the test header store is at height 10
+	for expiry, expected := range map[uint64]pubsub.ValidationResult{
+		9:  pubsub.ValidationReject,
+		10: pubsub.ValidationAccept,
+	} {
+		serv := newTestService(ctx, t, false)
+		serv.unmarshal = unmarshalerOf(fraud.ProofFactory[*headertest.DummyHeader]{
+			Type: fraudtest.DummyProofType,
+			New: func() fraud.Proof[*headertest.DummyHeader] {
+				return &expiringProof{&fraudtest.DummyProof[*headertest.DummyHeader]{}, expiry}
+			},
+		})
+		require.NoError(t, serv.Start(ctx))
+
+		msg := &pubsub.Message{Message: &pubsub_pb.Message{
+			Data: mustMarshal(t, fraudtest.NewValidProof[*headertest.DummyHeader]()),
+		}}
+		require.Equal(t, expected, serv.processIncoming(ctx, fraudtest.DummyProofType, "peer", msg), expiry)
+	}
+}
+
+func TestService_ProcessStatus(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	// an unset status is never taken for an accepted one
+	var unset ProcessStatus
+	require.Equal(t, ProcessUnknown, unset)
+
+	serv := newTestService(ctx, t, false)
+	require.NoError(t, serv.Start(ctx))
+
+	tests := []struct {
+		name   string
+		proof  fraud.Proof[*headertest.DummyHeader]
+		status ProcessStatus
+	}{
+		{"valid", fraudtest.NewValidProof[*headertest.DummyHeader](), ProcessAccepted},
+		{"invalid", fraudtest.NewInvalidProof[*headertest.DummyHeader](), ProcessRejected},
+		{"above threshold", fraudtest.NewValidProofAt[*headertest.DummyHeader](100), ProcessRejected},
+		// the header store has no headers above the head
+		{"header unavailable", fraudtest.NewValidProofAt[*headertest.DummyHeader](11), ProcessIgnored},
+	}
+	for _, tt := range tests {
+		status, err := serv.DryRun(ctx, tt.proof)
+		require.Equal(t, tt.status, status, tt.name)
+		require.Equal(t, tt.status == ProcessAccepted, err == nil, tt.name)
+	}
+
+	serv.headGetter = func(context.Context) (*headertest.DummyHeader, error) {
+		return nil, errors.New("head unavailable")
+	}
+	status, err := serv.Ingest(ctx, fraudtest.NewValidProof[*headertest.DummyHeader]())
+	require.Equal(t, ProcessIgnored, status)
+	require.Error(t, err)
+}
+
+func TestService_ResultPolicy(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(1)
+	require.NoError(t, err)
+	blacklist := newSyncBlacklist()
+	ps, err := pubsub.NewFloodSub(ctx, net.Hosts()[0],
+		pubsub.WithMessageSignaturePolicy(pubsub.StrictNoSign), pubsub.WithBlacklist(blacklist))
+	require.NoError(t, err)
+	serv := newTestServiceWithPubSub(ctx, t, ps, net.Hosts()[0], false,
+		WithResultPolicy[*headertest.DummyHeader](func(reason ProcessReason) pubsub.ValidationResult {
+			switch reason {
+			case ReasonVerifierFailed, ReasonInvalidProof:
+				return pubsub.ValidationIgnore
+			default:
+				return DefaultResultPolicy(reason)
+			}
+		}))
+	require.NoError(t, serv.Start(ctx))
+	require.NoError(t, serv.AddVerifier(fraudtest.DummyProofType,
+		func(proof fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+			if proof.Height() == 2 {
+				return false, errors.New("verifier failed")
+			}
+			return proof.Height() != 3, nil
+		}))
+
+	tests := []struct {
+		proof  fraud.Proof[*headertest.DummyHeader]
+		result pubsub.ValidationResult
+	}{
+		{&fraudtest.DummyProof[*headertest.DummyHeader]{ProofHeight: 1}, pubsub.ValidationIgnore},
+		{fraudtest.NewValidProofAt[*headertest.DummyHeader](2), pubsub.ValidationIgnore},
+		// not overridden
+		{fraudtest.NewValidProofAt[*headertest.DummyHeader](3), pubsub.ValidationReject},
+	}
+	for _, tt := range tests {
+		msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, tt.proof)}}
+		res := serv.processIncoming(ctx, tt.proof.Type(), "sender", msg)
+		require.Equal(t, tt.result, res, "proof at height %d", tt.proof.Height())
+	}
+
+	// blacklisting is processed in order, so once the marker is in, the sender would be as well
+	ps.BlacklistPeer("marker")
+	require.Eventually(t, func() bool {
+		return blacklist.Contains("marker")
+	}, time.Second, time.Millisecond)
+	require.False(t, blacklist.Contains("sender"))
+}
+
+func TestService_UnmarshalFailurePolicy(t *testing.T) {
+	tests := []struct {
+		policy      UnmarshalFailurePolicy
+		result      pubsub.ValidationResult
+		blacklisted bool
+	}{
+		{UnmarshalFailureBlacklist, pubsub.ValidationReject, true},
+		{UnmarshalFailureIgnore, pubsub.ValidationIgnore, false},
+		{UnmarshalFailureGraylist, pubsub.ValidationReject, false},
+	}
+	for _, tt := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		t.Cleanup(cancel)
+
+		net, err := mocknet.FullMeshLinked(1)
+		require.NoError(t, err)
+		blacklist := newSyncBlacklist()
+		ps, err := pubsub.NewFloodSub(ctx, net.Hosts()[0],
+			pubsub.WithMessageSignaturePolicy(pubsub.StrictNoSign), pubsub.WithBlacklist(blacklist))
+		require.NoError(t, err)
+		serv := newTestServiceWithPubSub(ctx, t, ps, net.Hosts()[0], false,
+			WithUnmarshalFailurePolicy[*headertest.DummyHeader](tt.policy))
+		require.NoError(t, serv.Start(ctx))
+
+		msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: []byte("garbage")}}
+		res := serv.processIncoming(ctx, fraudtest.DummyProofType, "sender", msg)
+		require.Equal(t, tt.result, res, tt.policy)
+
+		// blacklisting is processed in order, so once the marker is in, the sender would be as well
+		ps.BlacklistPeer("marker")
+		require.Eventually(t, func() bool {
+			return blacklist.Contains("marker")
+		}, time.Second, time.Millisecond)
+		require.Equal(t, tt.blacklisted, blacklist.Contains("sender"), tt.policy)
+	}
+}
+
+func TestService_VerifierBlacklistPeer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(1)
+	require.NoError(t, err)
+	blacklist := newSyncBlacklist()
+	ps, err := pubsub.NewFloodSub(ctx, net.Hosts()[0],
+		pubsub.WithMessageSignaturePolicy(pubsub.StrictNoSign), pubsub.WithBlacklist(blacklist))
+	require.NoError(t, err)
+	serv := newTestServiceWithPubSub(ctx, t, ps, net.Hosts()[0], false)
+	require.NoError(t, serv.Start(ctx))
+	require.NoError(t, serv.AddVerifier(fraudtest.DummyProofType,
+		func(proof fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+			if proof.Height() == 2 {
+				return false, fmt.Errorf("replayed proof: %w", fraud.ErrBlacklistPeer)
+			}
+			return false, nil
+		}))
+
+	for height, sender := range map[uint64]peer.ID{2: "replayer", 3: "sender"} {
+		proof := fraudtest.NewValidProofAt[*headertest.DummyHeader](height)
+		msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, proof)}}
+		res := serv.processIncoming(ctx, proof.Type(), sender, msg)
+		require.Equal(t, pubsub.ValidationReject, res)
+	}
+
+	require.Eventually(t, func() bool {
+		return blacklist.Contains("replayer")
+	}, time.Second, time.Millisecond)
+	// blacklisting is processed in order, so once the marker is in, the sender would be as well
+	ps.BlacklistPeer("marker")
+	require.Eventually(t, func() bool {
+		return blacklist.Contains("marker")
+	}, time.Second, time.Millisecond)
+	require.False(t, blacklist.Contains("sender"))
+}
+
+func TestService_BlacklistedPeers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	clock := newFakeClock()
+	serv := newTestService(ctx, t, false, WithClock[*headertest.DummyHeader](clock))
+	require.NoError(t, serv.Start(ctx))
+	require.Empty(t, serv.BlacklistedPeers())
+
+	invalid := &fraudtest.DummyProof[*headertest.DummyHeader]{ProofHeight: 1}
+	for _, sender := range []peer.ID{"first", "second", "first"} {
+		msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, invalid)}}
+		require.Equal(t, pubsub.ValidationReject, serv.processIncoming(ctx, invalid.Type(), sender, msg))
+		clock.Advance(time.Second)
+	}
+	// valid proofs don't get their senders blacklisted
+	valid := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, valid)}}
+	require.Equal(t, pubsub.ValidationAccept, serv.processIncoming(ctx, valid.Type(), "third", msg))
+
+	require.Equal(t, []BlacklistEntry{
+		{Peer: "first", Reason: ReasonInvalidProof, Time: time.Unix(0, 0)},
+		{Peer: "second", Reason: ReasonInvalidProof, Time: time.Unix(1, 0)},
+	}, serv.BlacklistedPeers())
+}
+
+func TestService_BlacklistedPeersBounded(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+	reader := withTestMeter(t)
+
+	clock := newFakeClock()
+	serv := newTestService(ctx, t, false, WithClock[*headertest.DummyHeader](clock))
+	require.NoError(t, serv.Start(ctx))
+
+	for i := 0; i <= maxBlacklistedPeers; i++ {
+		serv.blacklistPeer(peer.ID(fmt.Sprintf("peer-%d", i)), ReasonInvalidProof)
+		clock.Advance(time.Second)
+	}
+	// the earliest blacklisted peer is dropped from the records
+	entries := serv.BlacklistedPeers()
+	require.Len(t, entries, maxBlacklistedPeers)
+	require.Equal(t, peer.ID("peer-1"), entries[0].Peer)
+	require.Equal(t, peer.ID(fmt.Sprintf("peer-%d", maxBlacklistedPeers)), entries[len(entries)-1].Peer)
+	require.EqualValues(t, maxBlacklistedPeers, sumValue(collect(ctx, t, reader), "fraud_blacklisted_peers"))
+}
+
+func TestService_Unblacklist(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshLinked(2)
+	require.NoError(t, err)
+	hostA, hostB := net.Hosts()[0], net.Hosts()[1]
+	blacklist := NewBlacklist()
+	ps, err := pubsub.NewFloodSub(ctx, hostA,
+		pubsub.WithMessageSignaturePolicy(pubsub.StrictNoSign), pubsub.WithBlacklist(blacklist))
+	require.NoError(t, err)
+	servA := newTestServiceWithPubSub(ctx, t, ps, hostA, false, WithBlacklist[*headertest.DummyHeader](blacklist))
+	require.NoError(t, servA.Start(ctx))
+	servB := newTestServiceWithHost(ctx, t, hostB, false)
+	require.NoError(t, servB.Start(ctx))
+	require.ErrorIs(t, servB.Unblacklist(hostA.ID()), ErrUnblacklistUnsupported)
+
+	sub, err := servA.Subscribe(fraudtest.DummyProofType)
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	invalid := &fraudtest.DummyProof[*headertest.DummyHeader]{ProofHeight: 1}
+	msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: mustMarshal(t, invalid)}}
+	require.Equal(t, pubsub.ValidationReject, servA.processIncoming(ctx, invalid.Type(), hostB.ID(), msg))
+	require.Eventually(t, func() bool {
+		return blacklist.Contains(hostB.ID())
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, servA.Unblacklist(hostB.ID()))
+	require.False(t, blacklist.Contains(hostB.ID()))
+	require.Empty(t, servA.BlacklistedPeers())
+
+	// the PubSub ignores connections of blacklisted peers, so the peer connects once unblacklisted
+	_, err = net.ConnectPeers(hostA.ID(), hostB.ID())
+	require.NoError(t, err)
+	topic := servB.joinedTopics()[fraudtest.DummyProofType]
+	require.Eventually(t, func() bool {
+		return len(topic.ListPeers()) == 1
+	}, time.Second, time.Millisecond*10)
+
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, servB.Broadcast(ctx, proof))
+	got, err := sub.Proof(ctx)
+	require.NoError(t, err)
+	require.Equal(t, proof.HeaderHash(), got.HeaderHash())
+}
+
+func TestService_BatchVerifier(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	batches := make(chan int, 3)
+	verifier := func(_ context.Context, proofs []fraud.Proof[*headertest.DummyHeader]) ([]bool, error) {
+		batches <- len(proofs)
+		verdicts := make([]bool, len(proofs))
+		for i, proof := range proofs {
+			verdicts[i] = proof.Height() != 2
+		}
+		return verdicts, nil
+	}
+	serv := newTestService(ctx, t, false,
+		WithBatchVerifier[*headertest.DummyHeader](fraudtest.DummyProofType, verifier, time.Millisecond*200))
+	require.NoError(t, serv.Start(ctx))
+
+	statuses := make([]ProcessStatus, 3)
+	var wg sync.WaitGroup
+	for i := range statuses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			proof := fraudtest.NewValidProofAt[*headertest.DummyHeader](uint64(i + 1))
+			statuses[i], _ = serv.Ingest(ctx, proof)
+		}(i)
+	}
+	wg.Wait()
+
+	// all the proofs are verified in a single batch with their own verdicts
+	require.Equal(t, 3, <-batches)
+	require.Empty(t, batches)
+	require.Equal(t, []ProcessStatus{ProcessAccepted, ProcessRejected, ProcessAccepted}, statuses)
+
+	// the batch verifier failing fails all the proofs of the batch
+	failing := func(context.Context, []fraud.Proof[*headertest.DummyHeader]) ([]bool, error) {
+		return nil, errors.New("loading trusted state")
+	}
+	serv = newTestService(ctx, t, false,
+		WithBatchVerifier[*headertest.DummyHeader](fraudtest.DummyProofType, failing, time.Millisecond*50))
+	require.NoError(t, serv.Start(ctx))
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			proof := fraudtest.NewValidProofAt[*headertest.DummyHeader](uint64(i + 1))
+			statuses[i], _ = serv.Ingest(ctx, proof)
+		}(i)
+	}
+	wg.Wait()
+	require.Equal(t, []ProcessStatus{ProcessRejected, ProcessRejected}, statuses[:2])
+}
+
+func TestService_BroadcastExpiredProof(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	net, err := mocknet.FullMeshConnected(2)
+	require.NoError(t, err)
+	servA := newTestServiceWithHost(ctx, t, net.Hosts()[0], false)
+	require.NoError(t, servA.Start(ctx))
+	servB := newTestServiceWithHost(ctx, t, net.Hosts()[1], false)
+	require.NoError(t, servB.Start(ctx))
+	sub, err := servB.Subscribe(fraudtest.DummyProofType)
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	// the head of the test header store is at height 10
+	expired := &expiringProof{fraudtest.NewValidProofAt[*headertest.DummyHeader](1), 9}
+	require.ErrorIs(t, servA.Broadcast(ctx, expired), ErrProofExpired)
+	require.ErrorIs(t, servA.BroadcastTo(ctx, expired, net.Hosts()[1].ID()), ErrProofExpired)
+	_, err = servA.Get(ctx, fraudtest.DummyProofType)
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+
+	// the not yet expired proof is published
+	valid := &expiringProof{fraudtest.NewValidProofAt[*headertest.DummyHeader](2), 10}
+	require.Eventually(t, func() bool {
+		return len(servA.joinedTopics()[fraudtest.DummyProofType].ListPeers()) == 1
+	}, time.Second, time.Millisecond*10)
+	require.NoError(t, servA.Broadcast(ctx, valid))
+	proof, err := sub.Proof(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, proof.Height())
+}
+
+// syncBlacklist is a pubsub.Blacklist safe to be checked by tests while pubsub updates it.
+type syncBlacklist struct {
+	lk        sync.Mutex
+	blacklist pubsub.Blacklist
+}
+
+func newSyncBlacklist() *syncBlacklist {
+	return &syncBlacklist{blacklist: pubsub.NewMapBlacklist()}
+}
+
+func (b *syncBlacklist) Add(p peer.ID) bool {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	return b.blacklist.Add(p)
+}
+
+func (b *syncBlacklist) Contains(p peer.ID) bool {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	return b.blacklist.Contains(p)
+}
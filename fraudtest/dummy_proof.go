@@ -3,6 +3,7 @@ package fraudtest
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 
 	"github.com/celestiaorg/go-fraud"
 	"github.com/celestiaorg/go-header"
@@ -13,18 +14,25 @@ const DummyProofType fraud.ProofType = "DummyProof"
 type DummyProof[H header.Header[H]] struct {
 	Valid  bool
 	Panics bool
+	// ProofHeight overrides the default height of 1, so that distinct proofs can be made.
+	ProofHeight uint64 `json:",omitempty"`
 }
 
 func NewValidProof[H header.Header[H]]() *DummyProof[H] {
-	return &DummyProof[H]{true, false}
+	return &DummyProof[H]{Valid: true}
+}
+
+// NewValidProofAt creates a valid proof for the given height with a height-specific header hash.
+func NewValidProofAt[H header.Header[H]](height uint64) *DummyProof[H] {
+	return &DummyProof[H]{Valid: true, ProofHeight: height}
 }
 
 func NewInvalidProof[H header.Header[H]]() *DummyProof[H] {
-	return &DummyProof[H]{false, false}
+	return &DummyProof[H]{}
 }
 
 func NewPanickingProof[H header.Header[H]]() *DummyProof[H] {
-	return &DummyProof[H]{false, true}
+	return &DummyProof[H]{Panics: true}
 }
 
 func (m *DummyProof[H]) Type() fraud.ProofType {
@@ -32,11 +40,17 @@ func (m *DummyProof[H]) Type() fraud.ProofType {
 }
 
 func (m *DummyProof[H]) HeaderHash() []byte {
-	return []byte("hash")
+	if m.ProofHeight == 0 {
+		return []byte("hash")
+	}
+	return []byte(fmt.Sprintf("hash-%d", m.ProofHeight))
 }
 
 func (m *DummyProof[H]) Height() uint64 {
-	return 1
+	if m.ProofHeight == 0 {
+		return 1
+	}
+	return m.ProofHeight
 }
 
 func (m *DummyProof[H]) Validate(H) error {
@@ -2,30 +2,124 @@ package fraudtest
 
 import (
 	"context"
+	"sync"
+
+	"github.com/ipfs/go-datastore"
 
 	"github.com/celestiaorg/go-fraud"
 	"github.com/celestiaorg/go-header"
-	"github.com/celestiaorg/go-header/headertest"
 )
 
-type DummyService[H header.Header[H]] struct{}
+// subscriptionBufferSize is the amount of proofs buffered for a subscription.
+const subscriptionBufferSize = 16
+
+// DummyService is a no-op fraud.Service. When constructed with NewDummyService,
+// it keeps broadcasted proofs in memory so that they can be read back with Get
+// and delivers them to the subscriptions of their type.
+type DummyService[H header.Header[H]] struct {
+	lk     sync.Mutex
+	proofs map[fraud.ProofType][]fraud.Proof[H]
+	subs   map[fraud.ProofType]map[*subscription[H]]struct{}
+}
+
+// NewDummyService creates a DummyService backed by an in-memory map.
+func NewDummyService[H header.Header[H]]() *DummyService[H] {
+	return &DummyService[H]{
+		proofs: make(map[fraud.ProofType][]fraud.Proof[H]),
+		subs:   make(map[fraud.ProofType]map[*subscription[H]]struct{}),
+	}
+}
+
+// Broadcast keeps the proof and delivers it to the subscriptions of its type, waiting for
+// the subscriptions with full buffers to read it, unless they are cancelled meanwhile.
+func (d *DummyService[H]) Broadcast(ctx context.Context, p fraud.Proof[H]) error {
+	d.lk.Lock()
+	if d.proofs == nil {
+		d.lk.Unlock()
+		return nil
+	}
+	d.proofs[p.Type()] = append(d.proofs[p.Type()], p)
+	subs := make([]*subscription[H], 0, len(d.subs[p.Type()]))
+	for sub := range d.subs[p.Type()] {
+		subs = append(subs, sub)
+	}
+	d.lk.Unlock()
 
-func (d *DummyService[H]) Broadcast(context.Context, fraud.Proof[H]) error {
+	for _, sub := range subs {
+		select {
+		case sub.proofs <- p:
+		case <-sub.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 	return nil
 }
 
-func (d *DummyService[H]) Subscribe(fraud.ProofType) (fraud.Subscription[H], error) {
-	return &subscription[H]{}, nil
+func (d *DummyService[H]) Subscribe(proofType fraud.ProofType) (fraud.Subscription[H], error) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	if d.subs == nil {
+		return &subscription[H]{}, nil
+	}
+
+	sub := &subscription[H]{
+		proofs: make(chan fraud.Proof[H], subscriptionBufferSize),
+		done:   make(chan struct{}),
+	}
+	sub.cancel = func() {
+		d.lk.Lock()
+		defer d.lk.Unlock()
+		delete(d.subs[proofType], sub)
+		close(sub.done)
+	}
+	if d.subs[proofType] == nil {
+		d.subs[proofType] = make(map[*subscription[H]]struct{})
+	}
+	d.subs[proofType][sub] = struct{}{}
+	return sub, nil
+}
+
+func (d *DummyService[H]) AddVerifier(fraud.ProofType, fraud.Verifier[H]) error {
+	return nil
 }
 
-func (d *DummyService[H]) Get(context.Context, fraud.ProofType) ([]fraud.Proof[*headertest.DummyHeader], error) {
-	return nil, nil
+func (d *DummyService[H]) Get(_ context.Context, proofType fraud.ProofType) ([]fraud.Proof[H], error) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	if d.proofs == nil {
+		return nil, nil
+	}
+	proofs, ok := d.proofs[proofType]
+	if !ok {
+		return nil, datastore.ErrNotFound
+	}
+	return append([]fraud.Proof[H](nil), proofs...), nil
 }
 
-type subscription[H header.Header[H]] struct{}
+type subscription[H header.Header[H]] struct {
+	proofs     chan fraud.Proof[H]
+	done       chan struct{}
+	cancel     func()
+	cancelOnce sync.Once
+}
 
-func (s *subscription[H]) Proof(context.Context) (fraud.Proof[H], error) {
-	return nil, nil
+func (s *subscription[H]) Proof(ctx context.Context) (fraud.Proof[H], error) {
+	if s.proofs == nil {
+		return nil, nil
+	}
+	select {
+	case proof := <-s.proofs:
+		return proof, nil
+	case <-s.done:
+		return nil, fraud.ErrSubscriptionCancelled
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
-func (s *subscription[H]) Cancel() {}
+func (s *subscription[H]) Cancel() {
+	if s.cancel != nil {
+		s.cancelOnce.Do(s.cancel)
+	}
+}
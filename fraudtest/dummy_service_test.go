@@ -0,0 +1,84 @@
+package fraudtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/go-header/headertest"
+
+	"github.com/celestiaorg/go-fraud"
+)
+
+func TestDummyService_BroadcastGet(t *testing.T) {
+	ctx := context.Background()
+	serv := NewDummyService[*headertest.DummyHeader]()
+
+	_, err := serv.Get(ctx, DummyProofType)
+	require.ErrorIs(t, err, datastore.ErrNotFound)
+
+	proof := NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, serv.Broadcast(ctx, proof))
+
+	proofs, err := serv.Get(ctx, DummyProofType)
+	require.NoError(t, err)
+	require.Len(t, proofs, 1)
+	require.Equal(t, proof, proofs[0])
+}
+
+func TestDummyService_NoOp(t *testing.T) {
+	ctx := context.Background()
+	serv := &DummyService[*headertest.DummyHeader]{}
+
+	require.NoError(t, serv.Broadcast(ctx, NewValidProof[*headertest.DummyHeader]()))
+	proofs, err := serv.Get(ctx, DummyProofType)
+	require.NoError(t, err)
+	require.Empty(t, proofs)
+}
+
+func TestDummyService_Subscription(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	var serv fraud.Service[*headertest.DummyHeader] = NewDummyService[*headertest.DummyHeader]()
+	sub, err := serv.Subscribe(DummyProofType)
+	require.NoError(t, err)
+
+	proof := NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, serv.Broadcast(ctx, proof))
+
+	received, err := sub.Proof(ctx)
+	require.NoError(t, err)
+	require.Equal(t, proof, received)
+
+	sub.Cancel()
+	_, err = sub.Proof(ctx)
+	require.ErrorIs(t, err, fraud.ErrSubscriptionCancelled)
+}
+
+func TestDummyService_CancelBlockedSubscription(t *testing.T) {
+	serv := NewDummyService[*headertest.DummyHeader]()
+	sub, err := serv.Subscribe(DummyProofType)
+	require.NoError(t, err)
+
+	// fill the buffer of the subscription not reading the proofs
+	for i := 0; i < subscriptionBufferSize; i++ {
+		require.NoError(t, serv.Broadcast(context.Background(), NewValidProof[*headertest.DummyHeader]()))
+	}
+	broadcasted := make(chan error, 1)
+	go func() {
+		broadcasted <- serv.Broadcast(context.Background(), NewValidProof[*headertest.DummyHeader]())
+	}()
+
+	// cancelling the subscription unblocks the broadcast
+	sub.Cancel()
+	select {
+	case err = <-broadcasted:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("broadcast blocked on the cancelled subscription")
+	}
+}
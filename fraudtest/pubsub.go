@@ -0,0 +1,55 @@
+package fraudtest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/host"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+var (
+	memNetOnce sync.Once
+	// memNet is the in-memory network shared by the hosts of NewPubSubHost, so that any of them
+	// can be connected with ConnectHosts.
+	memNet mocknet.Mocknet
+)
+
+// NewPubSubHost creates a host in an in-memory network with a FloodSub router on top of it.
+// The hosts are not connected to each other until ConnectHosts is called.
+// The host is closed once the test finishes.
+func NewPubSubHost(ctx context.Context, t *testing.T) (host.Host, *pubsub.PubSub) {
+	t.Helper()
+	memNetOnce.Do(func() {
+		memNet = mocknet.New()
+	})
+	h, err := memNet.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = h.Close()
+	})
+	ps, err := pubsub.NewFloodSub(ctx, h, pubsub.WithMessageSignaturePolicy(pubsub.StrictNoSign))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h, ps
+}
+
+// ConnectHosts links and connects all the given hosts created with NewPubSubHost to each other.
+func ConnectHosts(hosts ...host.Host) error {
+	for i, a := range hosts {
+		for _, b := range hosts[i+1:] {
+			if _, err := memNet.LinkPeers(a.ID(), b.ID()); err != nil {
+				return err
+			}
+			if _, err := memNet.ConnectPeers(a.ID(), b.ID()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
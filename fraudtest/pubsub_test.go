@@ -0,0 +1,36 @@
+package fraudtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPubSubHosts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	t.Cleanup(cancel)
+
+	hostA, psA := NewPubSubHost(ctx, t)
+	hostB, psB := NewPubSubHost(ctx, t)
+	require.NoError(t, ConnectHosts(hostA, hostB))
+	require.Len(t, hostA.Network().Peers(), 1)
+
+	topicA, err := psA.Join("topic")
+	require.NoError(t, err)
+	topicB, err := psB.Join("topic")
+	require.NoError(t, err)
+	sub, err := topicB.Subscribe()
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	require.Eventually(t, func() bool {
+		return len(topicA.ListPeers()) == 1
+	}, time.Second, time.Millisecond*10)
+	require.NoError(t, topicA.Publish(ctx, []byte("proof")))
+	msg, err := sub.Next(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []byte("proof"), msg.Data)
+	require.Equal(t, hostA.ID(), msg.ReceivedFrom)
+}
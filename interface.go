@@ -2,6 +2,7 @@ package fraud
 
 import (
 	"context"
+	"errors"
 
 	"github.com/celestiaorg/go-header"
 )
@@ -12,9 +13,19 @@ type HeaderFetcher[H header.Header[H]] func(context.Context, uint64) (H, error)
 // HeadGetter aliases a function that is used to get current network head.
 type HeadGetter[H header.Header[H]] func(ctx context.Context) (H, error)
 
-// Verifier is a function that is executed as part of processing the incoming fraud proof
+// Verifier is a function that is executed as part of processing the incoming fraud proof.
+// Besides rejecting the proof, it may request the peer the proof was received from to be
+// blacklisted by returning an error wrapping ErrBlacklistPeer, e.g. for a known replayed proof.
 type Verifier[H header.Header[H]] func(fraud Proof[H]) (bool, error)
 
+// GetterVerifier is a Verifier that can additionally access stored proofs of any type through
+// the Getter, e.g. for a Proof that is only valid when a related Proof is not present.
+type GetterVerifier[H header.Header[H]] func(ctx context.Context, getter Getter[H], fraud Proof[H]) (bool, error)
+
+// BatchVerifier is a Verifier of multiple Proofs at once, e.g. to share an expensive setup
+// between them. It returns the verdicts in the order of the given Proofs.
+type BatchVerifier[H header.Header[H]] func(ctx context.Context, proofs []Proof[H]) ([]bool, error)
+
 // ProofUnmarshaler contains methods that allow an instance of ProofService
 // to access unmarshalers for various ProofTypes.
 type ProofUnmarshaler[H header.Header[H]] interface {
@@ -59,6 +70,12 @@ type Getter[H header.Header[H]] interface {
 	Get(context.Context, ProofType) ([]Proof[H], error)
 }
 
+// ErrBlacklistPeer is returned by Verifiers to request blacklisting the sender of the proof.
+var ErrBlacklistPeer = errors.New("fraud: verifier requested to blacklist the peer")
+
+// ErrSubscriptionCancelled is returned by Subscription.Proof once the Subscription is cancelled.
+var ErrSubscriptionCancelled = errors.New("fraud: subscription cancelled")
+
 // Subscription returns a valid proof if one is received on the topic.
 type Subscription[H header.Header[H]] interface {
 	// Proof returns already verified valid proof.
@@ -2,6 +2,7 @@ package fraud
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/ipfs/go-datastore"
 	"go.opentelemetry.io/otel"
@@ -15,7 +16,21 @@ var meter = otel.Meter("fraud")
 
 // WithMetrics enables metrics to monitor fraud proofs.
 func WithMetrics[H header.Header[H]](store Getter[H], unmarshaler ProofUnmarshaler[H]) {
+	storeErrors, err := meter.Int64Counter("fraud_store_errors",
+		metric.WithDescription("Errors while reading stored fraud proofs"),
+	)
+	if err != nil {
+		panic(err)
+	}
+	maxHeight, err := meter.Int64ObservableGauge("fraud_stored_max_height",
+		metric.WithDescription("Maximum height among stored fraud proofs"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
 	for _, proofType := range unmarshaler.List() {
+		proofType := proofType
 		counter, err := meter.Int64ObservableGauge(string(proofType),
 			metric.WithDescription("Stored fraud proof"),
 		)
@@ -23,23 +38,47 @@ func WithMetrics[H header.Header[H]](store Getter[H], unmarshaler ProofUnmarshal
 			panic(err)
 		}
 
+		// last and lastMax keep the last known amount and maximum height of stored proofs,
+		// so that store errors are not reported as the absence of proofs.
+		var last, lastMax atomic.Int64
 		callback := func(ctx context.Context, observer metric.Observer) error {
 			proofs, err := store.Get(ctx, proofType)
 			switch err {
 			case nil:
+				var highest uint64
+				for _, proof := range proofs {
+					if proof.Height() > highest {
+						highest = proof.Height()
+					}
+				}
+				last.Store(int64(len(proofs)))
+				lastMax.Store(int64(highest))
 				observer.ObserveInt64(counter, int64(len(proofs)),
 					metric.WithAttributes(
 						attribute.String("proof_type", string(proofType))))
+				observer.ObserveInt64(maxHeight, int64(highest),
+					metric.WithAttributes(
+						attribute.String("proof_type", string(proofType))))
 			case datastore.ErrNotFound:
+				last.Store(0)
+				lastMax.Store(0)
 				observer.ObserveInt64(counter, 0,
 					metric.WithAttributes(attribute.String("err", "not_found")))
 			default:
-				observer.ObserveInt64(counter, 0,
-					metric.WithAttributes(attribute.String("err", "unknown")))
+				storeErrors.Add(ctx, 1,
+					metric.WithAttributes(attribute.String("proof_type", string(proofType))))
+				observer.ObserveInt64(counter, last.Load(),
+					metric.WithAttributes(
+						attribute.String("proof_type", string(proofType))))
+				if highest := lastMax.Load(); highest > 0 {
+					observer.ObserveInt64(maxHeight, highest,
+						metric.WithAttributes(
+							attribute.String("proof_type", string(proofType))))
+				}
 			}
 			return nil
 		}
-		_, err = meter.RegisterCallback(callback, counter)
+		_, err = meter.RegisterCallback(callback, counter, maxHeight)
 		if err != nil {
 			panic(err)
 		}
@@ -0,0 +1,146 @@
+package fraud
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/celestiaorg/go-header/headertest"
+)
+
+const testProofType ProofType = "TestProof"
+
+func TestWithMetrics_StoreError(t *testing.T) {
+	ctx := context.Background()
+	reader := withTestMeter(t)
+
+	store := &testGetter{proofs: []Proof[*headertest.DummyHeader]{
+		testProof{height: 1}, testProof{height: 2}, testProof{height: 3},
+	}}
+	WithMetrics[*headertest.DummyHeader](store, testUnmarshaler{})
+
+	rm := collect(ctx, t, reader)
+	require.EqualValues(t, 3, gaugeValue(t, rm, string(testProofType)))
+
+	store.err = errors.New("corrupted")
+	rm = collect(ctx, t, reader)
+	// the gauge keeps the last value, while errors are counted separately
+	require.EqualValues(t, 3, gaugeValue(t, rm, string(testProofType)))
+	require.EqualValues(t, 1, counterValue(t, rm, "fraud_store_errors"))
+}
+
+func TestWithMetrics_MaxHeight(t *testing.T) {
+	ctx := context.Background()
+	reader := withTestMeter(t)
+
+	store := &testGetter{proofs: []Proof[*headertest.DummyHeader]{
+		testProof{height: 7}, testProof{height: 42}, testProof{height: 5},
+	}}
+	WithMetrics[*headertest.DummyHeader](store, testUnmarshaler{})
+
+	rm := collect(ctx, t, reader)
+	require.EqualValues(t, 42, gaugeValue(t, rm, "fraud_stored_max_height"))
+
+	// the last known max height is kept on store errors
+	store.err = errors.New("corrupted")
+	rm = collect(ctx, t, reader)
+	require.EqualValues(t, 42, gaugeValue(t, rm, "fraud_stored_max_height"))
+}
+
+// withTestMeter replaces the package meter with one backed by a manual reader.
+func withTestMeter(t *testing.T) sdkmetric.Reader {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	prev := meter
+	meter = provider.Meter("fraud")
+	t.Cleanup(func() {
+		meter = prev
+	})
+	return reader
+}
+
+func collect(ctx context.Context, t *testing.T, reader sdkmetric.Reader) metricdata.ResourceMetrics {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	return rm
+}
+
+func findMetric(t *testing.T, rm metricdata.ResourceMetrics, name string) metricdata.Metrics {
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m
+			}
+		}
+	}
+	t.Fatalf("metric %s is not found", name)
+	return metricdata.Metrics{}
+}
+
+func gaugeValue(t *testing.T, rm metricdata.ResourceMetrics, name string) int64 {
+	gauge, ok := findMetric(t, rm, name).Data.(metricdata.Gauge[int64])
+	require.True(t, ok)
+	require.Len(t, gauge.DataPoints, 1)
+	return gauge.DataPoints[0].Value
+}
+
+func counterValue(t *testing.T, rm metricdata.ResourceMetrics, name string) int64 {
+	sum, ok := findMetric(t, rm, name).Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	var total int64
+	for _, dp := range sum.DataPoints {
+		total += dp.Value
+	}
+	return total
+}
+
+type testGetter struct {
+	proofs []Proof[*headertest.DummyHeader]
+	err    error
+}
+
+func (g *testGetter) Get(context.Context, ProofType) ([]Proof[*headertest.DummyHeader], error) {
+	return g.proofs, g.err
+}
+
+type testUnmarshaler struct{}
+
+func (testUnmarshaler) List() []ProofType {
+	return []ProofType{testProofType}
+}
+
+func (testUnmarshaler) Unmarshal(ProofType, []byte) (Proof[*headertest.DummyHeader], error) {
+	return nil, errors.New("not implemented")
+}
+
+type testProof struct {
+	height uint64
+}
+
+func (p testProof) Type() ProofType {
+	return testProofType
+}
+
+func (p testProof) HeaderHash() []byte {
+	return nil
+}
+
+func (p testProof) Height() uint64 {
+	return p.height
+}
+
+func (p testProof) Validate(*headertest.DummyHeader) error {
+	return nil
+}
+
+func (p testProof) MarshalBinary() ([]byte, error) {
+	return nil, nil
+}
+
+func (p testProof) UnmarshalBinary([]byte) error {
+	return nil
+}
@@ -1,6 +1,7 @@
 package fraud
 
 import (
+	"bytes"
 	"context"
 	"encoding"
 	"fmt"
@@ -34,6 +35,63 @@ type Proof[H header.Header[H]] interface {
 	encoding.BinaryUnmarshaler
 }
 
+// Expiring is implemented by Proofs that are actionable only until the network reaches
+// the height encoded in them. Such Proofs are rejected once the network head is above it.
+type Expiring interface {
+	// Expiry returns the last network head height at which the Proof is actionable.
+	Expiry() uint64
+}
+
+// CanonicalMarshaler is implemented by Proofs whose MarshalBinary encoding is not deterministic,
+// e.g. because it depends on map iteration order, to provide a canonical encoding instead.
+// Logically equal Proofs must have equal canonical encodings.
+type CanonicalMarshaler interface {
+	CanonicalBytes() ([]byte, error)
+}
+
+// CanonicalBytes returns the canonical byte form of the Proof used to identify its content,
+// e.g. for deduplication, independently of the quirks of the encoding the Proof was received in.
+// It is the CanonicalMarshaler encoding of the Proof if implemented and the MarshalBinary
+// encoding otherwise, so Proofs with non-deterministic MarshalBinary should implement
+// CanonicalMarshaler. The encoding is prefixed with the ProofType, so that equally encoded
+// Proofs of different types differ.
+func CanonicalBytes[H header.Header[H]](p Proof[H]) ([]byte, error) {
+	var (
+		bin []byte
+		err error
+	)
+	if cm, ok := p.(CanonicalMarshaler); ok {
+		bin, err = cm.CanonicalBytes()
+	} else {
+		bin, err = p.MarshalBinary()
+	}
+	if err != nil {
+		return nil, err
+	}
+	canonical := make([]byte, 0, len(p.Type())+1+len(bin))
+	canonical = append(canonical, p.Type()...)
+	canonical = append(canonical, 0)
+	return append(canonical, bin...), nil
+}
+
+// ProofEqual reports whether the Proofs are the same, i.e. of the same type, height and header hash,
+// with equal CanonicalBytes, regardless of their concrete types and the encodings they were received in.
+// It returns an error if the canonical form of any Proof can't be obtained.
+func ProofEqual[H header.Header[H]](a, b Proof[H]) (bool, error) {
+	if a.Type() != b.Type() || a.Height() != b.Height() || !bytes.Equal(a.HeaderHash(), b.HeaderHash()) {
+		return false, nil
+	}
+	binA, err := CanonicalBytes(a)
+	if err != nil {
+		return false, err
+	}
+	binB, err := CanonicalBytes(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(binA, binB), nil
+}
+
 // OnProof subscribes to the given Fraud Proof topic via the given Subscriber.
 // In case a Fraud Proof is received, then the given handle function will be invoked.
 func OnProof[H header.Header[H]](ctx context.Context, sub Subscriber[H], p ProofType, handle func(proof Proof[H])) {
@@ -0,0 +1,126 @@
+package fraud_test
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/go-header/headertest"
+
+	"github.com/celestiaorg/go-fraud"
+	"github.com/celestiaorg/go-fraud/fraudtest"
+)
+
+func TestCanonicalBytes(t *testing.T) {
+	// the same proof, encoded differently
+	encodings := []string{
+		`{"Valid":true,"Panics":false}`,
+		`{ "Panics": false, "Valid": true }`,
+		`{"Valid":true}`,
+	}
+	var canonical [][]byte
+	for _, encoding := range encodings {
+		proof := &fraudtest.DummyProof[*headertest.DummyHeader]{}
+		require.NoError(t, proof.UnmarshalBinary([]byte(encoding)))
+		bin, err := fraud.CanonicalBytes[*headertest.DummyHeader](proof)
+		require.NoError(t, err)
+		canonical = append(canonical, bin)
+	}
+	for _, bin := range canonical[1:] {
+		require.Equal(t, canonical[0], bin)
+	}
+
+	// equally encoded proofs of different types differ
+	other := &otherProof{fraudtest.NewValidProof[*headertest.DummyHeader]()}
+	bin, err := fraud.CanonicalBytes[*headertest.DummyHeader](other)
+	require.NoError(t, err)
+	require.NotEqual(t, canonical[0], bin)
+}
+
+func TestCanonicalBytes_CanonicalMarshaler(t *testing.T) {
+	a := &setProof{DummyProof: fraudtest.NewValidProof[*headertest.DummyHeader](), set: map[string]struct{}{
+		"a": {}, "b": {}, "c": {},
+	}}
+	b := &setProof{DummyProof: fraudtest.NewValidProof[*headertest.DummyHeader](), set: map[string]struct{}{
+		"c": {}, "b": {}, "a": {},
+	}}
+	binA, err := fraud.CanonicalBytes[*headertest.DummyHeader](a)
+	require.NoError(t, err)
+	binB, err := fraud.CanonicalBytes[*headertest.DummyHeader](b)
+	require.NoError(t, err)
+	require.Equal(t, binA, binB)
+}
+
+func TestProofEqual(t *testing.T) {
+	decoded := &fraudtest.DummyProof[*headertest.DummyHeader]{}
+	require.NoError(t, decoded.UnmarshalBinary([]byte(`{ "Panics": false, "Valid": true }`)))
+
+	tests := []struct {
+		name  string
+		a, b  fraud.Proof[*headertest.DummyHeader]
+		equal bool
+	}{
+		{
+			name:  "equal",
+			a:     fraudtest.NewValidProof[*headertest.DummyHeader](),
+			b:     fraudtest.NewValidProof[*headertest.DummyHeader](),
+			equal: true,
+		},
+		{
+			name:  "differently encoded",
+			a:     fraudtest.NewValidProof[*headertest.DummyHeader](),
+			b:     decoded,
+			equal: true,
+		},
+		{
+			name: "canonical marshaler",
+			a: &setProof{DummyProof: fraudtest.NewValidProof[*headertest.DummyHeader](), set: map[string]struct{}{
+				"a": {}, "b": {},
+			}},
+			b: &setProof{DummyProof: fraudtest.NewValidProof[*headertest.DummyHeader](), set: map[string]struct{}{
+				"b": {}, "a": {},
+			}},
+			equal: true,
+		},
+		{
+			name: "different content",
+			a:    fraudtest.NewValidProof[*headertest.DummyHeader](),
+			b:    fraudtest.NewInvalidProof[*headertest.DummyHeader](),
+		},
+		{
+			name: "different height",
+			a:    fraudtest.NewValidProofAt[*headertest.DummyHeader](1),
+			b:    fraudtest.NewValidProofAt[*headertest.DummyHeader](2),
+		},
+		{
+			name: "different type",
+			a:    fraudtest.NewValidProof[*headertest.DummyHeader](),
+			b:    &otherProof{fraudtest.NewValidProof[*headertest.DummyHeader]()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			equal, err := fraud.ProofEqual(tt.a, tt.b)
+			require.NoError(t, err)
+			require.Equal(t, tt.equal, equal)
+		})
+	}
+}
+
+// setProof has a set, which encoding depends on the map iteration order,
+// so it implements CanonicalMarshaler.
+type setProof struct {
+	*fraudtest.DummyProof[*headertest.DummyHeader]
+	set map[string]struct{}
+}
+
+func (p *setProof) CanonicalBytes() ([]byte, error) {
+	elems := make([]string, 0, len(p.set))
+	for elem := range p.set {
+		elems = append(elems, elem)
+	}
+	sort.Strings(elems)
+	return []byte(strings.Join(elems, ",")), nil
+}
@@ -0,0 +1,68 @@
+package fraud
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"github.com/celestiaorg/go-header"
+)
+
+// ErrRawProof is returned when validating a RawProof, as it can't be decoded.
+var ErrRawProof = errors.New("fraud: raw proof can't be validated")
+
+// RawProof is an opaque Proof of a type without an unmarshaler, carrying only its type
+// and its encoded form. It can be stored and served or re-broadcast to peers as is,
+// e.g. by archival or relay nodes, but not validated.
+type RawProof[H header.Header[H]] struct {
+	ProofType ProofType
+	Data      []byte
+}
+
+func (p *RawProof[H]) Type() ProofType {
+	return p.ProofType
+}
+
+// HeaderHash returns the hash of the encoded form in place of the unknown header hash,
+// so that distinct RawProofs are stored apart.
+func (p *RawProof[H]) HeaderHash() []byte {
+	hash := sha256.Sum256(p.Data)
+	return hash[:]
+}
+
+// Height returns 0, as the height of the RawProof is unknown.
+func (p *RawProof[H]) Height() uint64 {
+	return 0
+}
+
+// Validate always fails with ErrRawProof.
+func (p *RawProof[H]) Validate(H) error {
+	return ErrRawProof
+}
+
+func (p *RawProof[H]) MarshalBinary() ([]byte, error) {
+	return p.Data, nil
+}
+
+func (p *RawProof[H]) UnmarshalBinary(data []byte) error {
+	p.Data = data
+	return nil
+}
+
+// RawFallback wraps the ProofUnmarshaler to unmarshal proofs of types it doesn't support
+// into RawProofs, instead of failing with ErrNoUnmarshaler.
+func RawFallback[H header.Header[H]](u ProofUnmarshaler[H]) ProofUnmarshaler[H] {
+	return rawFallback[H]{u}
+}
+
+type rawFallback[H header.Header[H]] struct {
+	ProofUnmarshaler[H]
+}
+
+func (u rawFallback[H]) Unmarshal(proofType ProofType, data []byte) (Proof[H], error) {
+	proof, err := u.ProofUnmarshaler.Unmarshal(proofType, data)
+	var errNoUnmarshaler *ErrNoUnmarshaler
+	if errors.As(err, &errNoUnmarshaler) {
+		return &RawProof[H]{ProofType: proofType, Data: data}, nil
+	}
+	return proof, err
+}
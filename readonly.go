@@ -0,0 +1,33 @@
+package fraud
+
+import (
+	"context"
+	"errors"
+
+	"github.com/celestiaorg/go-header"
+)
+
+// ErrReadOnly is returned by the mutating methods of the Service created with ReadOnlyService.
+var ErrReadOnly = errors.New("fraud: unsupported by read-only service")
+
+// ReadOnlyService wraps the Getter into a Service serving Get only, so that read-only components
+// can depend on the Service uniformly. Subscribe, Broadcast and AddVerifier fail with ErrReadOnly.
+func ReadOnlyService[H header.Header[H]](g Getter[H]) Service[H] {
+	return &readOnlyService[H]{Getter: g}
+}
+
+type readOnlyService[H header.Header[H]] struct {
+	Getter[H]
+}
+
+func (s *readOnlyService[H]) Subscribe(ProofType) (Subscription[H], error) {
+	return nil, ErrReadOnly
+}
+
+func (s *readOnlyService[H]) AddVerifier(ProofType, Verifier[H]) error {
+	return ErrReadOnly
+}
+
+func (s *readOnlyService[H]) Broadcast(context.Context, Proof[H]) error {
+	return ErrReadOnly
+}
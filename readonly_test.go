@@ -0,0 +1,32 @@
+package fraud_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/go-header/headertest"
+
+	"github.com/celestiaorg/go-fraud"
+	"github.com/celestiaorg/go-fraud/fraudtest"
+)
+
+func TestReadOnlyService(t *testing.T) {
+	ctx := context.Background()
+	getter := fraudtest.NewDummyService[*headertest.DummyHeader]()
+	proof := fraudtest.NewValidProof[*headertest.DummyHeader]()
+	require.NoError(t, getter.Broadcast(ctx, proof))
+
+	serv := fraud.ReadOnlyService[*headertest.DummyHeader](getter)
+	proofs, err := serv.Get(ctx, proof.Type())
+	require.NoError(t, err)
+	require.Equal(t, []fraud.Proof[*headertest.DummyHeader]{proof}, proofs)
+
+	_, err = serv.Subscribe(proof.Type())
+	require.ErrorIs(t, err, fraud.ErrReadOnly)
+	require.ErrorIs(t, serv.Broadcast(ctx, proof), fraud.ErrReadOnly)
+	require.ErrorIs(t, serv.AddVerifier(proof.Type(), func(fraud.Proof[*headertest.DummyHeader]) (bool, error) {
+		return true, nil
+	}), fraud.ErrReadOnly)
+}
@@ -1,6 +1,8 @@
 package fraud
 
 import (
+	"fmt"
+
 	"github.com/celestiaorg/go-header"
 )
 
@@ -10,6 +12,40 @@ type MultiUnmarshaler[H header.Header[H]] struct {
 	Unmarshalers map[ProofType]func([]byte) (Proof[H], error)
 }
 
+// ProofFactory pairs a ProofType with a constructor of empty Proofs of this type.
+type ProofFactory[H header.Header[H]] struct {
+	Type ProofType
+	New  func() Proof[H]
+}
+
+// Unmarshalers constructs a MultiUnmarshaler from the given ProofFactories.
+// The resulting MultiUnmarshaler instantiates a new Proof and calls its UnmarshalBinary.
+func Unmarshalers[H header.Header[H]](factories ...ProofFactory[H]) (*MultiUnmarshaler[H], error) {
+	u := &MultiUnmarshaler[H]{}
+	for _, f := range factories {
+		if err := u.Register(f.Type, f.New); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// Register registers a constructor of empty Proofs for the given ProofType.
+// Registered Proofs are unmarshalled with their UnmarshalBinary.
+func (d *MultiUnmarshaler[H]) Register(proofType ProofType, newProof func() Proof[H]) error {
+	if newProof == nil {
+		return fmt.Errorf("fraud: nil constructor for %s proof type", proofType)
+	}
+	if d.Unmarshalers == nil {
+		d.Unmarshalers = make(map[ProofType]func([]byte) (Proof[H], error))
+	}
+	d.Unmarshalers[proofType] = func(data []byte) (Proof[H], error) {
+		proof := newProof()
+		return proof, proof.UnmarshalBinary(data)
+	}
+	return nil
+}
+
 func (d MultiUnmarshaler[H]) List() []ProofType {
 	types := make([]ProofType, 0, len(d.Unmarshalers))
 	for tp := range d.Unmarshalers {
@@ -20,7 +56,7 @@ func (d MultiUnmarshaler[H]) List() []ProofType {
 
 func (d MultiUnmarshaler[H]) Unmarshal(proofType ProofType, data []byte) (Proof[H], error) {
 	uf, ok := d.Unmarshalers[proofType]
-	if !ok {
+	if !ok || uf == nil {
 		return nil, &ErrNoUnmarshaler{ProofType: proofType}
 	}
 
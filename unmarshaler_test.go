@@ -0,0 +1,73 @@
+package fraud_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/go-header/headertest"
+
+	"github.com/celestiaorg/go-fraud"
+	"github.com/celestiaorg/go-fraud/fraudtest"
+)
+
+const otherProofType fraud.ProofType = "OtherProof"
+
+type otherProof struct {
+	*fraudtest.DummyProof[*headertest.DummyHeader]
+}
+
+func (p *otherProof) Type() fraud.ProofType {
+	return otherProofType
+}
+
+func TestUnmarshalers(t *testing.T) {
+	u, err := fraud.Unmarshalers[*headertest.DummyHeader](
+		fraud.ProofFactory[*headertest.DummyHeader]{
+			Type: fraudtest.DummyProofType,
+			New: func() fraud.Proof[*headertest.DummyHeader] {
+				return &fraudtest.DummyProof[*headertest.DummyHeader]{}
+			},
+		},
+		fraud.ProofFactory[*headertest.DummyHeader]{
+			Type: otherProofType,
+			New: func() fraud.Proof[*headertest.DummyHeader] {
+				return &otherProof{&fraudtest.DummyProof[*headertest.DummyHeader]{}}
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []fraud.ProofType{fraudtest.DummyProofType, otherProofType}, u.List())
+
+	bin, err := fraudtest.NewValidProof[*headertest.DummyHeader]().MarshalBinary()
+	require.NoError(t, err)
+
+	proof, err := u.Unmarshal(fraudtest.DummyProofType, bin)
+	require.NoError(t, err)
+	require.Equal(t, fraudtest.DummyProofType, proof.Type())
+	require.NoError(t, proof.Validate(nil))
+
+	proof, err = u.Unmarshal(otherProofType, bin)
+	require.NoError(t, err)
+	require.Equal(t, otherProofType, proof.Type())
+	require.NoError(t, proof.Validate(nil))
+
+	_, err = u.Unmarshal("UnknownProof", bin)
+	require.ErrorAs(t, err, new(*fraud.ErrNoUnmarshaler))
+}
+
+func TestUnmarshalers_Nil(t *testing.T) {
+	_, err := fraud.Unmarshalers[*headertest.DummyHeader](
+		fraud.ProofFactory[*headertest.DummyHeader]{Type: fraudtest.DummyProofType},
+	)
+	require.Error(t, err)
+
+	u := &fraud.MultiUnmarshaler[*headertest.DummyHeader]{}
+	require.Error(t, u.Register(fraudtest.DummyProofType, nil))
+
+	u.Unmarshalers = map[fraud.ProofType]func([]byte) (fraud.Proof[*headertest.DummyHeader], error){
+		fraudtest.DummyProofType: nil,
+	}
+	_, err = u.Unmarshal(fraudtest.DummyProofType, nil)
+	require.ErrorAs(t, err, new(*fraud.ErrNoUnmarshaler))
+}
@@ -0,0 +1,31 @@
+package fraud
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/celestiaorg/go-header"
+)
+
+// ErrRejectedByVerifier is returned by VerifyProof when a Verifier rejects the Proof.
+var ErrRejectedByVerifier = errors.New("fraud: proof rejected by the verifier")
+
+// VerifyProof runs the given Verifiers on the Proof and validates it against the given header,
+// returning the first failure. The Verifiers run first, in the same order as in the validation
+// of incoming proofs by the services, so that proofs are refused for the same reasons.
+// Unlike the services, it doesn't fetch the header itself, which is useful for offline tooling and tests.
+func VerifyProof[H header.Header[H]](proof Proof[H], hdr H, verifiers ...Verifier[H]) error {
+	if hdr.Height() != proof.Height() {
+		return fmt.Errorf("fraud: header at height %d given for proof at height %d", hdr.Height(), proof.Height())
+	}
+	for _, verifier := range verifiers {
+		ok, err := verifier(proof)
+		if err != nil {
+			return fmt.Errorf("fraud: running the verifier: %w", err)
+		}
+		if !ok {
+			return ErrRejectedByVerifier
+		}
+	}
+	return proof.Validate(hdr)
+}
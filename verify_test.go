@@ -0,0 +1,81 @@
+package fraud_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/go-header/headertest"
+
+	"github.com/celestiaorg/go-fraud"
+	"github.com/celestiaorg/go-fraud/fraudtest"
+)
+
+func TestVerifyProof(t *testing.T) {
+	hdr := &headertest.DummyHeader{HeightI: 1}
+	accept := func(fraud.Proof[*headertest.DummyHeader]) (bool, error) { return true, nil }
+	reject := func(fraud.Proof[*headertest.DummyHeader]) (bool, error) { return false, nil }
+	errVerifier := errors.New("verifier failed")
+	fail := func(fraud.Proof[*headertest.DummyHeader]) (bool, error) { return false, errVerifier }
+
+	tests := []struct {
+		name      string
+		proof     *fraudtest.DummyProof[*headertest.DummyHeader]
+		verifiers []fraud.Verifier[*headertest.DummyHeader]
+		err       error
+		wantErr   bool
+	}{
+		{
+			name:  "valid",
+			proof: fraudtest.NewValidProofAt[*headertest.DummyHeader](1),
+		},
+		{
+			name:      "valid and accepted",
+			proof:     fraudtest.NewValidProofAt[*headertest.DummyHeader](1),
+			verifiers: []fraud.Verifier[*headertest.DummyHeader]{accept, accept},
+		},
+		{
+			name:    "invalid",
+			proof:   &fraudtest.DummyProof[*headertest.DummyHeader]{ProofHeight: 1},
+			wantErr: true,
+		},
+		{
+			name:    "header height mismatch",
+			proof:   fraudtest.NewValidProofAt[*headertest.DummyHeader](2),
+			wantErr: true,
+		},
+		{
+			name:      "rejected by verifier",
+			proof:     fraudtest.NewValidProofAt[*headertest.DummyHeader](1),
+			verifiers: []fraud.Verifier[*headertest.DummyHeader]{accept, reject},
+			err:       fraud.ErrRejectedByVerifier,
+		},
+		{
+			name:      "verifier fails",
+			proof:     fraudtest.NewValidProofAt[*headertest.DummyHeader](1),
+			verifiers: []fraud.Verifier[*headertest.DummyHeader]{fail, reject},
+			err:       errVerifier,
+		},
+		{
+			name:      "verifiers before validation",
+			proof:     &fraudtest.DummyProof[*headertest.DummyHeader]{ProofHeight: 1},
+			verifiers: []fraud.Verifier[*headertest.DummyHeader]{fail},
+			err:       errVerifier,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fraud.VerifyProof[*headertest.DummyHeader](tt.proof, hdr, tt.verifiers...)
+			switch {
+			case tt.err != nil:
+				require.ErrorIs(t, err, tt.err)
+			case tt.wantErr:
+				require.Error(t, err)
+				require.NotErrorIs(t, err, errVerifier)
+			default:
+				require.NoError(t, err)
+			}
+		})
+	}
+}